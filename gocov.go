@@ -23,6 +23,8 @@ package gocov
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 )
 
 type Package struct {
@@ -47,8 +49,20 @@ type Function struct {
 	// End is the end offset of the function.
 	End int
 
+	// StartLine and StartCol are the 1-based line and column of the
+	// start of the function's signature.
+	StartLine, StartCol int
+
+	// EndLine and EndCol are the 1-based line and column of the end
+	// of the function.
+	EndLine, EndCol int
+
 	// statements registered with this function.
 	Statements []*Statement
+
+	// Branches holds the branch points of the function, if branch
+	// coverage was recorded.
+	Branches []*Branch `json:",omitempty"`
 }
 
 type Statement struct {
@@ -58,13 +72,139 @@ type Statement struct {
 	// End is the end offset of the statement.
 	End int
 
+	// StartLine and StartCol are the 1-based line and column of the
+	// start of the statement.
+	StartLine, StartCol int
+
+	// EndLine and EndCol are the 1-based line and column of the end
+	// of the statement.
+	EndLine, EndCol int
+
 	// Reached is the number of times the statement was reached.
 	Reached int64
+
+	// Tests holds the names of the tests that reached the statement,
+	// in sorted order, if coverage was recorded per test.
+	Tests []string `json:",omitempty"`
+}
+
+// Branch describes a branch point: a condition whose outcomes, or a
+// clause whose entry, were recorded by instrumenting the source.
+type Branch struct {
+	// Kind is "if" or "for" for the condition of an if or for
+	// statement, "&&" or "||" for an operand of a conditional operator
+	// within such a condition, and "case" or "default" for a clause of
+	// a switch or select statement.
+	Kind string
+
+	// Start is the start offset of the condition or clause.
+	Start int
+
+	// End is the end offset of the condition, or of the clause's colon.
+	End int
+
+	// StartLine and StartCol are the 1-based line and column of the
+	// start of the branch point.
+	StartLine, StartCol int
+
+	// EndLine and EndCol are the 1-based line and column of the end
+	// of the branch point.
+	EndLine, EndCol int
+
+	// Taken records whether each outcome was taken: for conditions,
+	// true and then false; for clauses, only whether it was entered.
+	Taken []bool
+}
+
+// Tests returns the names of the tests that reached any of the
+// function's statements, in sorted order, if coverage was recorded
+// per test.
+func (f *Function) Tests() []string {
+	var tests []string
+	for _, s := range f.Statements {
+		tests = mergeTests(tests, s.Tests)
+	}
+	return tests
+}
+
+// MergeMode specifies how the Reached counts of matching statements are
+// combined when merging coverage information.
+type MergeMode int
+
+const (
+	// MergeSum adds the counts together.
+	MergeSum MergeMode = iota
+
+	// MergeMax takes the larger of the counts.
+	MergeMax
+
+	// MergeSet records only whether either statement was reached; the
+	// result is 1 if so, and 0 otherwise.
+	MergeSet
+)
+
+// Combine returns the result of combining the counts a and b.
+func (m MergeMode) Combine(a, b int64) int64 {
+	switch m {
+	case MergeMax:
+		if b > a {
+			return b
+		}
+		return a
+	case MergeSet:
+		if a > 0 || b > 0 {
+			return 1
+		}
+		return 0
+	default:
+		return a + b
+	}
+}
+
+func (m MergeMode) String() string {
+	switch m {
+	case MergeSum:
+		return "sum"
+	case MergeMax:
+		return "max"
+	case MergeSet:
+		return "set"
+	}
+	return fmt.Sprintf("MergeMode(%d)", int(m))
+}
+
+// ParseMergeMode returns the MergeMode with the given name: "sum", "max"
+// or "set".
+func ParseMergeMode(name string) (MergeMode, error) {
+	for _, m := range []MergeMode{MergeSum, MergeMax, MergeSet} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown merge mode %q", name)
 }
 
 // Accumulate will accumulate the coverage information from the provided
 // Package into this Package.
 func (p *Package) Accumulate(p2 *Package) error {
+	return p.Merge(p2, MergeSum)
+}
+
+// Merge will merge the coverage information from the provided Package
+// into this Package, combining the statement counts according to mode.
+// The packages must be structurally identical; if they are not, an
+// error is returned and this Package is left unmodified.
+func (p *Package) Merge(p2 *Package, mode MergeMode) error {
+	if err := p.match(p2); err != nil {
+		return err
+	}
+	for i, f := range p.Functions {
+		f.merge(p2.Functions[i], mode)
+	}
+	return nil
+}
+
+func (p *Package) match(p2 *Package) error {
 	if p.Name != p2.Name {
 		return fmt.Errorf("Names do not match: %q != %q", p.Name, p2.Name)
 	}
@@ -72,8 +212,7 @@ func (p *Package) Accumulate(p2 *Package) error {
 		return fmt.Errorf("Function counts do not match: %d != %d", len(p.Functions), len(p2.Functions))
 	}
 	for i, f := range p.Functions {
-		err := f.Accumulate(p2.Functions[i])
-		if err != nil {
+		if err := f.match(p2.Functions[i]); err != nil {
 			return err
 		}
 	}
@@ -83,6 +222,14 @@ func (p *Package) Accumulate(p2 *Package) error {
 // Accumulate will accumulate the coverage information from the provided
 // Function into this Function.
 func (f *Function) Accumulate(f2 *Function) error {
+	if err := f.match(f2); err != nil {
+		return err
+	}
+	f.merge(f2, MergeSum)
+	return nil
+}
+
+func (f *Function) match(f2 *Function) error {
 	if f.Name != f2.Name {
 		return fmt.Errorf("Names do not match: %q != %q", f.Name, f2.Name)
 	}
@@ -96,20 +243,360 @@ func (f *Function) Accumulate(f2 *Function) error {
 		return fmt.Errorf("Number of statements do not match: %d != %d", len(f.Statements), len(f2.Statements))
 	}
 	for i, s := range f.Statements {
-		err := s.Accumulate(f2.Statements[i])
-		if err != nil {
+		if err := s.match(f2.Statements[i]); err != nil {
 			return err
 		}
 	}
+	if len(f.Branches) > 0 && len(f2.Branches) > 0 && len(f.Branches) != len(f2.Branches) {
+		return fmt.Errorf("Number of branches do not match: %d != %d", len(f.Branches), len(f2.Branches))
+	}
 	return nil
 }
 
+func (f *Function) merge(f2 *Function, mode MergeMode) {
+	for i, s := range f.Statements {
+		s.merge(f2.Statements[i], mode)
+	}
+	f.mergeBranches(f2)
+}
+
+// mergeBranches merges the branch outcomes recorded in f2 into f, if
+// only f2 recorded them or both recorded the same branch points.
+func (f *Function) mergeBranches(f2 *Function) {
+	if len(f.Branches) == 0 {
+		for _, b2 := range f2.Branches {
+			b := *b2
+			b.Taken = append([]bool(nil), b2.Taken...)
+			f.Branches = append(f.Branches, &b)
+		}
+		return
+	}
+	if len(f.Branches) != len(f2.Branches) {
+		return
+	}
+	for i, b := range f.Branches {
+		b2 := f2.Branches[i]
+		if b.Kind != b2.Kind || len(b.Taken) != len(b2.Taken) {
+			continue
+		}
+		for j, taken := range b2.Taken {
+			b.Taken[j] = b.Taken[j] || taken
+		}
+	}
+}
+
 // Accumulate will accumulate the coverage information from the provided
 // Statement into this Statement.
 func (s *Statement) Accumulate(s2 *Statement) error {
+	if err := s.match(s2); err != nil {
+		return err
+	}
+	s.merge(s2, MergeSum)
+	return nil
+}
+
+func (s *Statement) merge(s2 *Statement, mode MergeMode) {
+	s.Reached = mode.Combine(s.Reached, s2.Reached)
+	s.Tests = mergeTests(s.Tests, s2.Tests)
+}
+
+// mergeTests returns the sorted union of the sorted test name lists
+// a and b.
+func mergeTests(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return append([]string(nil), b...)
+	}
+	merged := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+func (s *Statement) match(s2 *Statement) error {
 	if s.Start != s2.Start || s.End != s2.End {
 		return fmt.Errorf("Source ranges do not match: %d-%d != %d-%d", s.Start, s.End, s2.Start, s2.End)
 	}
-	s.Reached += s2.Reached
 	return nil
 }
+
+// Mismatch describes coverage information that Reconcile could not merge.
+type Mismatch struct {
+	// Package is the name of the package containing the unmatched entry.
+	Package string
+
+	// Function is the unmatched function, or the function containing
+	// the unmatched statement. It may be nil if the packages themselves
+	// could not be reconciled.
+	Function *Function
+
+	// Statement is the unmatched statement, or nil if the whole
+	// function could not be matched.
+	Statement *Statement
+
+	// Reason describes why the entry could not be reconciled.
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	switch {
+	case m.Function == nil:
+		return fmt.Sprintf("%s: %s", m.Package, m.Reason)
+	case m.Statement == nil:
+		return fmt.Sprintf("%s: %s (%s): %s", m.Package, m.Function.Name, m.Function.File, m.Reason)
+	}
+	return fmt.Sprintf("%s: %s (%s): statement %d-%d: %s",
+		m.Package, m.Function.Name, m.Function.File,
+		m.Statement.Start, m.Statement.End, m.Reason)
+}
+
+// Reconcile merges the coverage information from the provided Package
+// into this Package, combining statement counts according to mode, while
+// tolerating changes to the source code between the runs that produced
+// them.
+//
+// This Package is treated as the reference: functions are matched by
+// the base name of their file and their name, so that coverage from
+// different checkouts of the source may be reconciled. Function
+// literals, whose names record their position, are instead matched by
+// their position relative to the enclosing function, as statements
+// are: by their offset relative to the start or end of the enclosing
+// function, or failing that by their line and column relative to the
+// start of the enclosing function. Entries in p2 that cannot be
+// matched are not merged, and are returned as Mismatches.
+func (p *Package) Reconcile(p2 *Package, mode MergeMode) []Mismatch {
+	if p.Name != p2.Name {
+		reason := fmt.Sprintf("Names do not match: %q != %q", p.Name, p2.Name)
+		return []Mismatch{{Package: p2.Name, Reason: reason}}
+	}
+
+	matches := p.MatchFunctions(p2)
+	var mismatches []Mismatch
+	for _, f2 := range p2.Functions {
+		f := matches[f2]
+		if f == nil {
+			mismatches = append(mismatches, Mismatch{
+				Package:  p2.Name,
+				Function: f2,
+				Reason:   "function not found",
+			})
+			continue
+		}
+		for _, s2 := range f.reconcile(f2, mode) {
+			mismatches = append(mismatches, Mismatch{
+				Package:   p2.Name,
+				Function:  f2,
+				Statement: s2,
+				Reason:    "statement not found",
+			})
+		}
+	}
+	return mismatches
+}
+
+// MatchFunctions matches the functions of p2 with those of this
+// Package as Reconcile does, returning a map from each function of p2
+// to its match. Functions that cannot be matched are absent from the
+// map.
+func (p *Package) MatchFunctions(p2 *Package) map[*Function]*Function {
+	type funcKey struct{ file, name string }
+	functions := make(map[funcKey][]*Function)
+	for _, f := range p.Functions {
+		if !f.isLiteral() {
+			key := funcKey{fileBase(f.File), f.Name}
+			functions[key] = append(functions[key], f)
+		}
+	}
+	matches := make(map[*Function]*Function)
+	var literals2 []*Function
+	for _, f2 := range p2.Functions {
+		if f2.isLiteral() {
+			literals2 = append(literals2, f2)
+			continue
+		}
+		key := funcKey{fileBase(f2.File), f2.Name}
+		candidates := functions[key]
+		if len(candidates) == 0 {
+			continue
+		}
+		functions[key] = candidates[1:]
+		matches[f2] = candidates[0]
+	}
+
+	// Match function literals within matched enclosing functions,
+	// outermost first so that the functions enclosing nested
+	// literals are matched before them.
+	enclosing := enclosingFunctions(p.Functions)
+	enclosing2 := enclosingFunctions(p2.Functions)
+	literals := make(map[*Function][]*Function)
+	for _, f := range p.Functions {
+		if e := enclosing[f]; e != nil {
+			literals[e] = append(literals[e], f)
+		}
+	}
+	sort.SliceStable(literals2, func(i, j int) bool {
+		return literals2[i].End-literals2[i].Start > literals2[j].End-literals2[j].Start
+	})
+	matchers := make(map[*Function]*positionMatcher)
+	for _, f2 := range literals2 {
+		e2 := enclosing2[f2]
+		e := matches[e2]
+		if e == nil {
+			continue
+		}
+		m := matchers[e]
+		if m == nil {
+			m = newPositionMatcher(e)
+			for _, lit := range literals[e] {
+				m.add(lit, lit.Start, lit.End, lit.StartLine, lit.StartCol, lit.EndLine, lit.EndCol)
+			}
+			matchers[e] = m
+		}
+		if f, ok := m.take(e2, f2.Start, f2.End, f2.StartLine, f2.StartCol, f2.EndLine, f2.EndCol).(*Function); ok {
+			matches[f2] = f
+		}
+	}
+	return matches
+}
+
+// isLiteral reports whether f is a function literal, which is named
+// after its position in the file.
+func (f *Function) isLiteral() bool {
+	return strings.HasPrefix(f.Name, "@")
+}
+
+// enclosingFunctions returns a map from each function literal to the
+// innermost function in the same file that contains it.
+func enclosingFunctions(functions []*Function) map[*Function]*Function {
+	enclosing := make(map[*Function]*Function)
+	for _, lit := range functions {
+		if !lit.isLiteral() {
+			continue
+		}
+		for _, f := range functions {
+			if f == lit || f.File != lit.File || f.Start > lit.Start || f.End < lit.End {
+				continue
+			}
+			if e := enclosing[lit]; e == nil || f.End-f.Start < e.End-e.Start {
+				enclosing[lit] = f
+			}
+		}
+	}
+	return enclosing
+}
+
+// fileBase returns the last element of a file path, which may have
+// been recorded on another operating system. The files of a package
+// are all in one directory, so this identifies a file within it.
+func fileBase(file string) string {
+	return file[strings.LastIndexAny(file, `/\`)+1:]
+}
+
+// reconcile merges the statement counts of f2 into f, returning the
+// statements of f2 that could not be matched.
+func (f *Function) reconcile(f2 *Function, mode MergeMode) (unmatched []*Statement) {
+	matches := f.MatchStatements(f2)
+	for _, s2 := range f2.Statements {
+		s := matches[s2]
+		if s == nil {
+			unmatched = append(unmatched, s2)
+			continue
+		}
+		s.merge(s2, mode)
+	}
+	f.mergeBranches(f2)
+	return unmatched
+}
+
+// MatchStatements matches the statements of f2 with those of this
+// Function as Reconcile does, by their offset relative to the start or
+// end of the function, or failing that by their line and column
+// relative to the start of the function. It returns a map from each
+// statement of f2 to its match; statements that cannot be matched are
+// absent from the map.
+func (f *Function) MatchStatements(f2 *Function) map[*Statement]*Statement {
+	m := newPositionMatcher(f)
+	for _, s := range f.Statements {
+		m.add(s, s.Start, s.End, s.StartLine, s.StartCol, s.EndLine, s.EndCol)
+	}
+	matches := make(map[*Statement]*Statement)
+	for _, s2 := range f2.Statements {
+		if s, ok := m.take(f2, s2.Start, s2.End, s2.StartLine, s2.StartCol, s2.EndLine, s2.EndCol).(*Statement); ok {
+			matches[s2] = s
+		}
+	}
+	return matches
+}
+
+// positionMatcher matches entries within a function, such as its
+// statements, with those within another version of the function, by
+// their position relative to the function.
+type positionMatcher struct {
+	f         *Function
+	fromStart map[positionKey][]interface{}
+	fromEnd   map[positionKey][]interface{}
+	fromLine  map[lineKey][]interface{}
+	matched   map[interface{}]bool
+}
+
+type positionKey struct{ offset, length int }
+
+type lineKey struct{ line, col, lines, endCol int }
+
+func newPositionMatcher(f *Function) *positionMatcher {
+	return &positionMatcher{
+		f:         f,
+		fromStart: make(map[positionKey][]interface{}),
+		fromEnd:   make(map[positionKey][]interface{}),
+		fromLine:  make(map[lineKey][]interface{}),
+		matched:   make(map[interface{}]bool),
+	}
+}
+
+// add adds an entry with the given position within the function.
+func (m *positionMatcher) add(v interface{}, start, end, startLine, startCol, endLine, endCol int) {
+	length := end - start
+	startKey := positionKey{start - m.f.Start, length}
+	endKey := positionKey{m.f.End - start, length}
+	m.fromStart[startKey] = append(m.fromStart[startKey], v)
+	m.fromEnd[endKey] = append(m.fromEnd[endKey], v)
+	if startLine > 0 && m.f.StartLine > 0 {
+		key := lineKey{startLine - m.f.StartLine, startCol, endLine - startLine, endCol}
+		m.fromLine[key] = append(m.fromLine[key], v)
+	}
+}
+
+// take returns the first entry not already taken whose position
+// relative to the function matches the given position relative to
+// f2, or nil if there is none.
+func (m *positionMatcher) take(f2 *Function, start, end, startLine, startCol, endLine, endCol int) interface{} {
+	first := func(candidates []interface{}) interface{} {
+		for _, v := range candidates {
+			if !m.matched[v] {
+				m.matched[v] = true
+				return v
+			}
+		}
+		return nil
+	}
+	length := end - start
+	v := first(m.fromStart[positionKey{start - f2.Start, length}])
+	if v == nil {
+		v = first(m.fromEnd[positionKey{f2.End - start, length}])
+	}
+	if v == nil && startLine > 0 && f2.StartLine > 0 {
+		v = first(m.fromLine[lineKey{startLine - f2.StartLine, startCol, endLine - startLine, endCol}])
+	}
+	return v
+}
@@ -188,8 +188,23 @@ func (a *annotator) printFunctionSource(fn *gocov.Function) error {
 		file.SetLinesForContent(data)
 	}
 
+	// Coverage data produced by older versions of gocov does not
+	// record line numbers; compute them from the offsets instead.
+	lineno := fn.StartLine
+	if lineno == 0 {
+		lineno = file.Line(file.Pos(fn.Start))
+	}
+	startLine := func(s *gocov.Statement) int {
+		if s.StartLine == 0 {
+			return file.Line(file.Pos(s.Start))
+		}
+		return s.StartLine
+	}
+
+	// The statements slice is consumed below, so gather the tests
+	// that reached the function first.
+	tests := fn.Tests()
 	statements := fn.Statements[:]
-	lineno := file.Line(file.Pos(fn.Start))
 	lines := strings.Split(string(data)[fn.Start:fn.End], "\n")
 	linenoWidth := int(math.Log10(float64(lineno+len(lines)))) + 1
 	fmt.Println()
@@ -204,7 +219,7 @@ func (a *annotator) printFunctionSource(fn *gocov.Function) error {
 		statementFound := false
 		hit := false
 		for j := 0; j < len(statements); j++ {
-			start := file.Line(file.Pos(statements[j].Start))
+			start := startLine(statements[j])
 			// FIXME instrumentation no longer records statements
 			// in line order, as function literals are processed
 			// after the body of a function. If/when that's changed,
@@ -232,6 +247,9 @@ func (a *annotator) printFunctionSource(fn *gocov.Function) error {
 			fmt.Printf("%*d %s\t%s\n", linenoWidth, lineno, hitmiss, line)
 		}
 	}
+	if len(tests) > 0 {
+		fmt.Printf("\nReached by: %s\n", strings.Join(tests, ", "))
+	}
 	fmt.Println()
 
 	return nil
@@ -0,0 +1,248 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/axw/gocov"
+	goPackages "golang.org/x/tools/go/packages"
+)
+
+var apiFlags = flag.NewFlagSet("api", flag.ExitOnError)
+
+// apiSurface describes the public API of a package.
+type apiSurface struct {
+	// importable records whether the package can be imported from
+	// anywhere: it is not a main package, nor an internal package.
+	importable bool
+
+	// types holds the names of the package's types whose exported
+	// methods are public: exported types, and unexported types with
+	// an exported alias.
+	types map[string]bool
+}
+
+// isPublic reports whether the named function is part of the public
+// API: an exported function, or an exported method of a public type,
+// in an importable package.
+func (s *apiSurface) isPublic(name string) bool {
+	if s == nil || !s.importable {
+		return false
+	}
+	recv, fn := splitFunctionName(name)
+	if !token.IsExported(fn) {
+		return false
+	}
+	return recv == "" || s.types[recv]
+}
+
+// loadAPISurfaces loads the type information of the named packages
+// and returns their public API surfaces.
+func loadAPISurfaces(pkgPaths []string) (map[string]*apiSurface, error) {
+	pkgs, err := goPackages.Load(&goPackages.Config{
+		Mode: goPackages.NeedName | goPackages.NeedTypes,
+	}, pkgPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %v", err)
+	}
+	surfaces := make(map[string]*apiSurface)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		surfaces[pkg.PkgPath] = newAPISurface(pkg.Types)
+	}
+	return surfaces, nil
+}
+
+// newAPISurface returns the public API surface of pkg.
+func newAPISurface(pkg *types.Package) *apiSurface {
+	s := &apiSurface{
+		importable: pkg.Name() != "main",
+		types:      make(map[string]bool),
+	}
+	for _, elem := range strings.Split(pkg.Path(), "/") {
+		if elem == "internal" {
+			s.importable = false
+		}
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !tn.Exported() {
+			continue
+		}
+		if !tn.IsAlias() {
+			s.types[name] = true
+			continue
+		}
+		// An exported alias makes the methods of the type it
+		// denotes public, even if that type is unexported.
+		t := types.Unalias(tn.Type())
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = types.Unalias(ptr.Elem())
+		}
+		if named, ok := t.(*types.Named); ok && named.Obj().Pkg() == pkg {
+			s.types[named.Origin().Obj().Name()] = true
+		}
+	}
+	return s
+}
+
+// apiFunction is a named function, with the function literals it
+// contains.
+type apiFunction struct {
+	*gocov.Function
+	literals []*gocov.Function
+}
+
+// statementCoverage returns the statement coverage of the function
+// including its function literals.
+func (f *apiFunction) statementCoverage() (reached, total int) {
+	return statementCoverage(append([]*gocov.Function{f.Function}, f.literals...)...)
+}
+
+// apiFunctions returns the named functions in pkg, ordered by file and
+// position, attributing each function literal to the innermost named
+// function containing it. Function literals outside of any named
+// function, such as in package-level variable declarations, are
+// returned separately.
+func apiFunctions(pkg *gocov.Package) (functions []*apiFunction, orphans []*gocov.Function) {
+	for _, file := range packageFiles(pkg) {
+		var named []*apiFunction
+		for _, fn := range file.functions {
+			if !strings.HasPrefix(fn.Name, "@") {
+				named = append(named, &apiFunction{Function: fn})
+			}
+		}
+		for _, fn := range file.functions {
+			if !strings.HasPrefix(fn.Name, "@") {
+				continue
+			}
+			var enclosing *apiFunction
+			for _, f := range named {
+				if f.Start <= fn.Start && fn.End <= f.End && (enclosing == nil || f.Start > enclosing.Start) {
+					enclosing = f
+				}
+			}
+			if enclosing == nil {
+				orphans = append(orphans, fn)
+			} else {
+				enclosing.literals = append(enclosing.literals, fn)
+			}
+		}
+		functions = append(functions, named...)
+	}
+	return functions, orphans
+}
+
+// apiCoverage accumulates coverage of public or internal functions.
+type apiCoverage struct {
+	reached, total int
+	called, funcs  int
+}
+
+func (c *apiCoverage) add(reached, total int) {
+	c.reached += reached
+	c.total += total
+	if total > 0 {
+		c.funcs++
+		if reached > 0 {
+			c.called++
+		}
+	}
+}
+
+func (c *apiCoverage) merge(c2 *apiCoverage) {
+	c.reached += c2.reached
+	c.total += c2.total
+	c.called += c2.called
+	c.funcs += c2.funcs
+}
+
+func (c *apiCoverage) String() string {
+	return fmt.Sprintf("%.2f%% (%d/%d), %d/%d functions called",
+		percentage(c.reached, c.total), c.reached, c.total, c.called, c.funcs)
+}
+
+// printAPIReport prints the coverage of each public function, and the
+// coverage of the public and internal functions of each package and
+// in total.
+func printAPIReport(w io.Writer, r *report, surfaces map[string]*apiSurface) {
+	tw := tabwriter.NewWriter(w, 0, 8, 0, '\t', 0)
+	var totalPublic, totalInternal apiCoverage
+	for _, pkg := range r.packages {
+		surface := surfaces[pkg.Name]
+		var public, internal apiCoverage
+		functions, orphans := apiFunctions(pkg)
+		for _, fn := range functions {
+			reached, total := fn.statementCoverage()
+			if !surface.isPublic(fn.Name) {
+				internal.add(reached, total)
+				continue
+			}
+			public.add(reached, total)
+			fmt.Fprintf(tw, "%s/%s\t %s\t %.2f%% (%d/%d)\n",
+				pkg.Name, filepath.Base(fn.File), fn.Name,
+				percentage(reached, total), reached, total)
+		}
+		for _, fn := range orphans {
+			internal.add(statementCoverage(fn))
+		}
+		fmt.Fprintf(tw, "%s\t public\t %s\n", pkg.Name, &public)
+		fmt.Fprintf(tw, "%s\t internal\t %s\n", pkg.Name, &internal)
+		fmt.Fprintln(tw)
+		totalPublic.merge(&public)
+		totalInternal.merge(&internal)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Public API Coverage: %s\n", &totalPublic)
+	fmt.Fprintf(w, "Internal Coverage: %s\n", &totalInternal)
+}
+
+func apiReport() (rc int) {
+	apiFlags.Parse(flag.Args()[1:])
+	report, err := readReport(apiFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var pkgPaths []string
+	for _, pkg := range report.packages {
+		pkgPaths = append(pkgPaths, pkg.Name)
+	}
+	surfaces, err := loadAPISurfaces(pkgPaths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printAPIReport(os.Stdout, report, surfaces)
+	return 0
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestAPISurface(t *testing.T) {
+	imports := make(testImporter)
+	pkg := checkPackage(t, "example.com/p", `package p
+
+type T struct{}
+
+func (T) Exported()   {}
+func (T) unexported() {}
+
+type t struct{}
+
+func (t) Exported() {}
+
+type u struct{}
+
+func (*u) Exported() {}
+
+// U makes the methods of u public.
+type U = u
+
+type G[K any] struct{}
+
+func (G[K]) Exported() {}
+
+func F() {}
+func f() {}
+`, imports)
+	s := newAPISurface(pkg)
+	tests := []struct {
+		name   string
+		public bool
+	}{
+		{"F", true},
+		{"f", false},
+		{"T.Exported", true},
+		{"T.unexported", false},
+		{"t.Exported", false},
+		{"u.Exported", true},
+		{"G[K].Exported", true},
+		{"@10:2", false},
+	}
+	for _, test := range tests {
+		if public := s.isPublic(test.name); public != test.public {
+			t.Errorf("isPublic(%q) = %v, want %v", test.name, public, test.public)
+		}
+	}
+
+	for _, path := range []string{"example.com/internal", "example.com/p/internal/q"} {
+		pkg := checkPackage(t, path, "package q\n\nfunc F() {}\n", imports)
+		if s := newAPISurface(pkg); s.isPublic("F") {
+			t.Errorf("%s: F is public in an internal package", path)
+		}
+	}
+	pkg = checkPackage(t, "example.com/cmd", "package main\n\nfunc F() {}\n\nfunc main() {}\n", imports)
+	if s := newAPISurface(pkg); s.isPublic("F") {
+		t.Errorf("F is public in a main package")
+	}
+
+	// Packages that could not be loaded have no public API.
+	var missing *apiSurface
+	if missing.isPublic("F") {
+		t.Errorf("F is public in a package that was not loaded")
+	}
+}
+
+// apiPackage returns a package with function literals inside named
+// functions, inside other function literals, and at package level.
+func apiPackage() *gocov.Package {
+	return &gocov.Package{Name: "example.com/p", Functions: []*gocov.Function{
+		testFunction("@1:9", "/src/p/p.go", 1, 0),
+		testFunction("F", "/src/p/p.go", 10, 1, 1, 1),
+		testFunction("@11:2", "/src/p/p.go", 11, 0),
+		testFunction("@12:3", "/src/p/p.go", 12),
+		testFunction("T.Exported", "/src/p/p.go", 20, 0, 0),
+		testFunction("@21:2", "/src/p/p.go", 21, 1),
+		testFunction("t.Exported", "/src/p/q.go", 1, 1),
+		testFunction("f", "/src/p/q.go", 10, 0),
+	}}
+}
+
+func TestAPIFunctions(t *testing.T) {
+	functions, orphans := apiFunctions(apiPackage())
+	var got []string
+	for _, fn := range functions {
+		s := fn.Name + ":"
+		for _, lit := range fn.literals {
+			s += " " + lit.Name
+		}
+		got = append(got, s)
+	}
+	want := []string{"F: @11:2 @12:3", "T.Exported: @21:2", "t.Exported:", "f:"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("got %q, want %q", got[i], want[i])
+		}
+	}
+	if len(orphans) != 1 || orphans[0].Name != "@1:9" {
+		t.Errorf("got orphans %v, want [@1:9]", orphans)
+	}
+
+	if reached, total := functions[1].statementCoverage(); reached != 1 || total != 3 {
+		t.Errorf("T.Exported coverage = %d/%d, want 1/3", reached, total)
+	}
+}
+
+func TestPrintAPIReport(t *testing.T) {
+	r := testReport(t, apiPackage())
+	surfaces := map[string]*apiSurface{
+		"example.com/p": {importable: true, types: map[string]bool{"T": true}},
+	}
+	var buf bytes.Buffer
+	printAPIReport(&buf, r, surfaces)
+	checkGolden(t, "api.txt", buf.Bytes())
+}
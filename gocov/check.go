@@ -0,0 +1,87 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/axw/gocov"
+)
+
+// thresholds holds the minimum coverage percentages required by
+// checkThresholds; a zero value disables the corresponding check.
+type thresholds struct {
+	total    float64
+	pkg      float64
+	function float64
+}
+
+func percentage(reached, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(reached) / float64(total) * 100
+}
+
+// checkThresholds returns a description of each part of the report
+// whose statement coverage is below the corresponding threshold.
+// Functions and packages without statements are not checked, nor is
+// the total if there are no statements at all.
+func checkThresholds(r *report, t thresholds) []string {
+	var violations []string
+	var totalReached, totalStatements int
+	for _, pkg := range r.packages {
+		reached, total := statementCoverage(pkg.Functions...)
+		totalReached += reached
+		totalStatements += total
+		if percent := percentage(reached, total); t.pkg > 0 && total > 0 && percent < t.pkg {
+			violations = append(violations, fmt.Sprintf(
+				"package %s: %.2f%% (%d/%d) is below %.2f%%",
+				pkg.Name, percent, reached, total, t.pkg))
+		}
+		if t.function <= 0 {
+			continue
+		}
+		for _, fn := range pkg.Functions {
+			reached, total := statementCoverage(fn)
+			if total == 0 {
+				continue
+			}
+			if percent := percentage(reached, total); percent < t.function {
+				violations = append(violations, fmt.Sprintf(
+					"function %s: %.2f%% (%d/%d) is below %.2f%%",
+					functionDisplayName(pkg, fn), percent, reached, total, t.function))
+			}
+		}
+	}
+	if percent := percentage(totalReached, totalStatements); t.total > 0 && totalStatements > 0 && percent < t.total {
+		violations = append(violations, fmt.Sprintf(
+			"total: %.2f%% (%d/%d) is below %.2f%%",
+			percent, totalReached, totalStatements, t.total))
+	}
+	return violations
+}
+
+// functionDisplayName returns the name of fn qualified by its package,
+// as accepted by "gocov annotate".
+func functionDisplayName(pkg *gocov.Package, fn *gocov.Function) string {
+	return pkg.Name + "/" + fn.Name
+}
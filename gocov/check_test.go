@@ -0,0 +1,115 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+// testFunction returns a function in the named file with a statement
+// on each line following its first, reached the given number of times.
+func testFunction(name, file string, line int, reached ...int64) *gocov.Function {
+	fn := &gocov.Function{
+		Name:      name,
+		File:      file,
+		Start:     line * 100,
+		End:       (line+len(reached)+1)*100 + 1,
+		StartLine: line,
+		StartCol:  1,
+		EndLine:   line + len(reached) + 1,
+		EndCol:    2,
+	}
+	for i, n := range reached {
+		l := line + i + 1
+		fn.Statements = append(fn.Statements, &gocov.Statement{
+			Start:     l*100 + 1,
+			End:       l*100 + 10,
+			StartLine: l,
+			StartCol:  2,
+			EndLine:   l,
+			EndCol:    11,
+			Reached:   n,
+		})
+	}
+	return fn
+}
+
+// testReport returns a report of the given packages.
+func testReport(t *testing.T, packages ...*gocov.Package) *report {
+	r := newReport()
+	for _, pkg := range packages {
+		if err := r.addPackage(pkg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestCheckThresholds(t *testing.T) {
+	r := testReport(t,
+		&gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+			testFunction("Full", "/src/a/a.go", 1, 1, 2),
+			testFunction("Half", "/src/a/a.go", 10, 1, 0),
+			testFunction("Empty", "/src/a/a.go", 20),
+		}},
+		&gocov.Package{Name: "example.com/b", Functions: []*gocov.Function{
+			testFunction("None", "/src/b/b.go", 1, 0, 0, 0, 0),
+		}},
+		// A package whose only file was excluded.
+		&gocov.Package{Name: "example.com/c"},
+	)
+	tests := []struct {
+		thresholds thresholds
+		violations []string
+	}{{
+		thresholds: thresholds{},
+	}, {
+		thresholds: thresholds{total: 37.5},
+	}, {
+		thresholds: thresholds{total: 40},
+		violations: []string{"total: 37.50% (3/8) is below 40.00%"},
+	}, {
+		thresholds: thresholds{pkg: 50},
+		violations: []string{"package example.com/b: 0.00% (0/4) is below 50.00%"},
+	}, {
+		thresholds: thresholds{function: 60},
+		violations: []string{
+			"function example.com/a/Half: 50.00% (1/2) is below 60.00%",
+			"function example.com/b/None: 0.00% (0/4) is below 60.00%",
+		},
+	}}
+	for _, test := range tests {
+		violations := checkThresholds(r, test.thresholds)
+		if !reflect.DeepEqual(violations, test.violations) {
+			t.Errorf("%+v: expected %q, got %q", test.thresholds, test.violations, violations)
+		}
+	}
+}
+
+func TestCheckThresholdsNoStatements(t *testing.T) {
+	r := testReport(t, &gocov.Package{Name: "example.com/c"})
+	if violations := checkThresholds(r, thresholds{total: 50, pkg: 50, function: 50}); len(violations) > 0 {
+		t.Errorf("unexpected violations: %q", violations)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/axw/gocov"
+)
+
+// timeNow returns the time recorded in Cobertura reports; tests replace
+// it to produce reproducible output.
+var timeNow = time.Now
+
+type coberturaCoverage struct {
+	XMLName         xml.Name          `xml:"coverage"`
+	LineRate        float64           `xml:"line-rate,attr"`
+	BranchRate      float64           `xml:"branch-rate,attr"`
+	LinesCovered    int               `xml:"lines-covered,attr"`
+	LinesValid      int               `xml:"lines-valid,attr"`
+	BranchesCovered int               `xml:"branches-covered,attr"`
+	BranchesValid   int               `xml:"branches-valid,attr"`
+	Complexity      float64           `xml:"complexity,attr"`
+	Version         string            `xml:"version,attr"`
+	Timestamp       int64             `xml:"timestamp,attr"`
+	Sources         []string          `xml:"sources>source"`
+	Packages        coberturaPackages `xml:"packages"`
+}
+
+type coberturaPackages struct {
+	Packages []coberturaPackage `xml:"package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    coberturaClasses `xml:"classes"`
+}
+
+type coberturaClasses struct {
+	Classes []coberturaClass `xml:"class"`
+}
+
+type coberturaClass struct {
+	Name       string           `xml:"name,attr"`
+	Filename   string           `xml:"filename,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Methods    coberturaMethods `xml:"methods"`
+	Lines      coberturaLines   `xml:"lines"`
+}
+
+type coberturaMethods struct {
+	Methods []coberturaMethod `xml:"method"`
+}
+
+type coberturaMethod struct {
+	Name       string         `xml:"name,attr"`
+	Signature  string         `xml:"signature,attr"`
+	LineRate   float64        `xml:"line-rate,attr"`
+	BranchRate float64        `xml:"branch-rate,attr"`
+	Complexity float64        `xml:"complexity,attr"`
+	Lines      coberturaLines `xml:"lines"`
+}
+
+type coberturaLines struct {
+	Lines []coberturaLine `xml:"line"`
+}
+
+type coberturaLine struct {
+	Number int   `xml:"number,attr"`
+	Hits   int64 `xml:"hits,attr"`
+}
+
+// coberturaLinesFor returns the Cobertura line elements for the given
+// functions, along with the number of lines that were reached.
+func coberturaLinesFor(functions ...*gocov.Function) (lines coberturaLines, covered int) {
+	hits, numbers := lineHits(functions...)
+	for _, n := range numbers {
+		lines.Lines = append(lines.Lines, coberturaLine{Number: n, Hits: hits[n]})
+		if hits[n] > 0 {
+			covered++
+		}
+	}
+	return lines, covered
+}
+
+func lineRate(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return float64(covered) / float64(valid)
+}
+
+// printCoberturaReport writes the report in Cobertura XML format. Each
+// source file is represented as a class, and each function as a method.
+func printCoberturaReport(w io.Writer, r *report) error {
+	var filenames []string
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			filenames = append(filenames, fn.File)
+		}
+	}
+	source := commonDir(filenames)
+
+	coverage := coberturaCoverage{
+		Version:   "gocov",
+		Timestamp: timeNow().UnixNano() / int64(time.Millisecond),
+		Sources:   []string{source},
+	}
+	for _, pkg := range r.packages {
+		var pkgCovered, pkgValid int
+		p := coberturaPackage{Name: pkg.Name}
+		for _, file := range packageFiles(pkg) {
+			filename, err := filepath.Rel(source, file.name)
+			if err != nil {
+				filename = file.name
+			}
+			class := coberturaClass{
+				Name:     filepath.Base(file.name),
+				Filename: filepath.ToSlash(filename),
+			}
+			for _, fn := range file.functions {
+				lines, covered := coberturaLinesFor(fn)
+				class.Methods.Methods = append(class.Methods.Methods, coberturaMethod{
+					Name:     fn.Name,
+					LineRate: lineRate(covered, len(lines.Lines)),
+					Lines:    lines,
+				})
+			}
+			lines, covered := coberturaLinesFor(file.functions...)
+			class.Lines = lines
+			class.LineRate = lineRate(covered, len(lines.Lines))
+			p.Classes.Classes = append(p.Classes.Classes, class)
+			pkgCovered += covered
+			pkgValid += len(lines.Lines)
+		}
+		p.LineRate = lineRate(pkgCovered, pkgValid)
+		coverage.Packages.Packages = append(coverage.Packages.Packages, p)
+		coverage.LinesCovered += pkgCovered
+		coverage.LinesValid += pkgValid
+	}
+	coverage.LineRate = lineRate(coverage.LinesCovered, coverage.LinesValid)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(coverage); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// commonDir returns the longest directory containing all of the
+// given files.
+func commonDir(filenames []string) string {
+	if len(filenames) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(filenames[0])
+	for _, filename := range filenames[1:] {
+		for dir != filepath.Dir(dir) && !strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
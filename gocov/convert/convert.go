@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocov/internal/covdata"
 	"github.com/axw/gocov/gocovutil"
 	"go/ast"
 	"go/parser"
@@ -32,8 +33,10 @@ import (
 	"golang.org/x/tools/cover"
 	goPackages "golang.org/x/tools/go/packages"
 	"io"
+	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -41,23 +44,123 @@ func marshalJson(w io.Writer, packages []*gocov.Package) error {
 	return json.NewEncoder(w).Encode(struct{ Packages []*gocov.Package }{packages})
 }
 
+// Config controls which source files are included when converting
+// coverage profiles. The zero value includes all files.
+type Config struct {
+	// SkipGenerated skips generated files, identified by a
+	// "// Code generated ... DO NOT EDIT." comment.
+	SkipGenerated bool
+
+	// Exclude holds slash-separated glob patterns, as accepted by
+	// path.Match, of files to skip. A "**" element matches any number
+	// of path elements. Patterns are matched against both the file's
+	// import path (the package path followed by the file name) and
+	// its absolute path.
+	Exclude []string
+}
+
+// ConvertProfiles converts the textual coverage profiles (as written by
+// "go test -coverprofile") to gocov's JSON interchange format.
+//
+// All files are included; use Config.ConvertProfiles to skip some.
 func ConvertProfiles(filenames ...string) ([]byte, error) {
-	var (
-		ps gocovutil.Packages
-	)
+	return new(Config).ConvertProfiles(filenames...)
+}
 
-	for i := range filenames {
-		converter := converter{
-			packages: make(map[string]*gocov.Package),
+// ConvertTestProfiles is like ConvertProfiles, but takes a map from test
+// name to the profile recording the coverage of that test alone. The
+// names of the tests that reached each statement are recorded in the
+// statement's Tests field. The coverage in the untested profiles, such
+// as those of packages without tests, is included without being
+// attributed to any test.
+func ConvertTestProfiles(profiles map[string]string, untested ...string) ([]byte, error) {
+	return new(Config).ConvertTestProfiles(profiles, untested...)
+}
+
+// ConvertDirs converts the binary coverage data files written to the
+// given directories (GOCOVERDIR) by binaries built with "go build -cover"
+// to gocov's JSON interchange format.
+func ConvertDirs(dirs ...string) ([]byte, error) {
+	return new(Config).ConvertDirs(dirs...)
+}
+
+// ConvertProfiles converts the textual coverage profiles (as written by
+// "go test -coverprofile") to gocov's JSON interchange format.
+func (c *Config) ConvertProfiles(filenames ...string) ([]byte, error) {
+	profileSets := make([]profileSet, len(filenames))
+	for i, filename := range filenames {
+		profiles, err := cover.ParseProfiles(filename)
+		if err != nil {
+			return nil, err
+		}
+		profileSets[i].profiles = profiles
+	}
+	return c.convertProfileSets(profileSets)
+}
+
+// ConvertTestProfiles is like ConvertProfiles, but takes a map from test
+// name to the profile recording the coverage of that test alone. The
+// names of the tests that reached each statement are recorded in the
+// statement's Tests field. The coverage in the untested profiles, such
+// as those of packages without tests, is included without being
+// attributed to any test.
+func (c *Config) ConvertTestProfiles(profiles map[string]string, untested ...string) ([]byte, error) {
+	tests := make([]string, 0, len(profiles))
+	for test := range profiles {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	profileSets := make([]profileSet, len(tests), len(tests)+len(untested))
+	for i, test := range tests {
+		p, err := cover.ParseProfiles(profiles[test])
+		if err != nil {
+			return nil, err
 		}
-		profiles, err := cover.ParseProfiles(filenames[i])
+		profileSets[i] = profileSet{profiles: p, test: test}
+	}
+	for _, filename := range untested {
+		p, err := cover.ParseProfiles(filename)
 		if err != nil {
 			return nil, err
 		}
+		profileSets = append(profileSets, profileSet{profiles: p})
+	}
+	return c.convertProfileSets(profileSets)
+}
 
-		mapUniqPackageNames := make(map[string]interface{})
-		uniqPackageNames := make([]string, 0, len(profiles))
-		for _, profile := range profiles {
+// ConvertDirs converts the binary coverage data files written to the
+// given directories (GOCOVERDIR) by binaries built with "go build -cover"
+// to gocov's JSON interchange format.
+func (c *Config) ConvertDirs(dirs ...string) ([]byte, error) {
+	profileSets := make([]profileSet, len(dirs))
+	for i, dir := range dirs {
+		profiles, err := covdata.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		profileSets[i].profiles = profiles
+	}
+	return c.convertProfileSets(profileSets)
+}
+
+// profileSet holds the profiles parsed from a single coverage file or
+// directory, and the name of the test that produced them, if known.
+type profileSet struct {
+	profiles []*cover.Profile
+	test     string
+}
+
+func (c *Config) convertProfileSets(profileSets []profileSet) ([]byte, error) {
+	var (
+		ps gocovutil.Packages
+	)
+
+	// Load the packages of all the profiles at once, rather than for
+	// each set: there may be a set for every test.
+	mapUniqPackageNames := make(map[string]interface{})
+	var uniqPackageNames []string
+	for _, set := range profileSets {
+		for _, profile := range set.profiles {
 			packageName := path.Dir(profile.FileName)
 
 			if _, ok := mapUniqPackageNames[packageName]; ok {
@@ -67,25 +170,39 @@ func ConvertProfiles(filenames ...string) ([]byte, error) {
 			mapUniqPackageNames[packageName] = nil
 			uniqPackageNames = append(uniqPackageNames, packageName)
 		}
-
+	}
+	pkgmap := make(map[string]*goPackages.Package)
+	if len(uniqPackageNames) > 0 {
 		packages, err := goPackages.Load(&goPackages.Config{
 			Mode: goPackages.NeedName | goPackages.NeedCompiledGoFiles,
 		}, uniqPackageNames...)
 		if err != nil {
 			return nil, fmt.Errorf("load packages: %v", err)
 		}
-
-		pkgmap := make(map[string]*goPackages.Package, len(packages))
 		for _, pkg := range packages {
 			pkgmap[pkg.PkgPath] = pkg
 		}
+	}
+
+	for _, set := range profileSets {
+		profiles := set.profiles
+		converter := converter{
+			packages: make(map[string]*gocov.Package),
+			config:   c,
+		}
 
 		for _, profile := range profiles {
 			pkgpath, filename := path.Split(profile.FileName)
 			pkgpath = strings.TrimSuffix(pkgpath, "/")
 			pkg := pkgmap[pkgpath]
+			if pkg == nil {
+				return nil, fmt.Errorf("convert profile %s: package %q not found", profile.FileName, pkgpath)
+			}
 			for _, abspath := range pkg.CompiledGoFiles {
 				if filepath.Base(abspath) == filename {
+					if c.excluded(profile.FileName, abspath) {
+						continue
+					}
 					if err := converter.convertProfile(profile, abspath, pkg.PkgPath); err != nil {
 						return nil, fmt.Errorf("convert profile %s: %w", profile.FileName, err)
 					}
@@ -93,8 +210,16 @@ func ConvertProfiles(filenames ...string) ([]byte, error) {
 			}
 		}
 
+		// The same package may appear in many profiles, such as when
+		// they were written by "go test -coverpkg".
+		mode := profileMergeMode(profiles)
 		for _, pkg := range converter.packages {
-			ps.AddPackage(pkg)
+			if set.test != "" {
+				recordTest(pkg, set.test)
+			}
+			if err := ps.MergePackage(pkg, mode); err != nil {
+				return nil, fmt.Errorf("merge package %s: %w", pkg.Name, err)
+			}
 		}
 	}
 	buf := bytes.Buffer{}
@@ -104,8 +229,72 @@ func ConvertProfiles(filenames ...string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// profileMergeMode returns the mode in which to merge the coverage
+// from profiles with that of other profiles: in "set" mode counts
+// only record whether a block was reached, so they must not be summed.
+func profileMergeMode(profiles []*cover.Profile) gocov.MergeMode {
+	for _, p := range profiles {
+		if p.Mode == "set" {
+			return gocov.MergeSet
+		}
+	}
+	return gocov.MergeSum
+}
+
+// recordTest records test as having reached each of the reached
+// statements in pkg.
+func recordTest(pkg *gocov.Package, test string) {
+	for _, f := range pkg.Functions {
+		for _, s := range f.Statements {
+			if s.Reached > 0 {
+				s.Tests = []string{test}
+			}
+		}
+	}
+}
+
+// excluded reports whether the file with the given import path and
+// absolute path matches any of the Exclude patterns.
+func (c *Config) excluded(importPath, absPath string) bool {
+	for _, pattern := range c.Exclude {
+		if matchGlob(pattern, importPath) || matchGlob(pattern, filepath.ToSlash(absPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash-separated name matches pattern,
+// where each element of pattern is matched by path.Match except "**",
+// which matches any number of elements.
+func matchGlob(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
 type converter struct {
 	packages map[string]*gocov.Package
+	config   *Config
 }
 
 // wrapper for gocov.Statement
@@ -115,31 +304,45 @@ type statement struct {
 }
 
 func (c *converter) convertProfile(p *cover.Profile, absFilePath, pkgPath string) error {
-	pkg := c.packages[pkgPath]
-	if pkg == nil {
-		pkg = &gocov.Package{Name: pkgPath}
-		c.packages[pkgPath] = pkg
-	}
 	// Find function and statement extents; create corresponding
 	// gocov.Functions and gocov.Statements, and keep a separate
 	// slice of gocov.Statements so we can match them with profile
 	// blocks.
-	extents, err := findFuncs(absFilePath)
+	extents, generated, ignored, err := findFuncs(absFilePath)
 	if err != nil {
 		return err
 	}
+	if ignored || generated && c.config != nil && c.config.SkipGenerated {
+		return nil
+	}
+	pkg := c.packages[pkgPath]
+	if pkg == nil {
+		pkg = &gocov.Package{Name: pkgPath}
+		c.packages[pkgPath] = pkg
+	}
 
 	var stmts []statement
 	for _, fe := range extents {
 		f := &gocov.Function{
-			Name:  fe.name,
-			File:  absFilePath,
-			Start: fe.startOffset,
-			End:   fe.endOffset,
+			Name:      fe.name,
+			File:      absFilePath,
+			Start:     fe.startOffset,
+			End:       fe.endOffset,
+			StartLine: fe.startLine,
+			StartCol:  fe.startCol,
+			EndLine:   fe.endLine,
+			EndCol:    fe.endCol,
 		}
 		for _, se := range fe.stmts {
 			s := statement{
-				Statement:  &gocov.Statement{Start: se.startOffset, End: se.endOffset},
+				Statement: &gocov.Statement{
+					Start:     se.startOffset,
+					End:       se.endOffset,
+					StartLine: se.startLine,
+					StartCol:  se.startCol,
+					EndLine:   se.endLine,
+					EndCol:    se.endCol,
+				},
 				StmtExtent: se,
 			}
 			f.Statements = append(f.Statements, s.Statement)
@@ -147,38 +350,173 @@ func (c *converter) convertProfile(p *cover.Profile, absFilePath, pkgPath string
 		}
 		pkg.Functions = append(pkg.Functions, f)
 	}
-	// For each profile block in the file, find the statement(s) it
-	// covers and increment the Reached field(s).
-	blocks := p.Blocks
+	// Statements are not visited in source order (function literals
+	// are visited after their enclosing function), so match each one
+	// with the profile blocks independently.
+	blocks := sortedBlocks(p.Blocks)
 	for _, s := range stmts {
-		for i, b := range blocks {
-			if b.StartLine > s.endLine || (b.StartLine == s.endLine && b.StartCol >= s.endCol) {
-				// Past the end of the statement
-				blocks = blocks[i:]
-				break
-			}
-			if b.EndLine < s.startLine || (b.EndLine == s.startLine && b.EndCol <= s.startCol) {
-				// Before the beginning of the statement
-				continue
-			}
+		if b := blocks.find(s.StmtExtent); b != nil {
 			s.Reached += int64(b.Count)
-			break
 		}
 	}
 
 	return nil
 }
 
-// findFuncs parses the file and returns a slice of FuncExtent descriptors.
-func findFuncs(name string) ([]*FuncExtent, error) {
+// blockList is a list of profile blocks sorted by start position.
+type blockList []cover.ProfileBlock
+
+func sortedBlocks(blocks []cover.ProfileBlock) blockList {
+	sorted := make(blockList, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return positionBefore(sorted[i].StartLine, sorted[i].StartCol, sorted[j].StartLine, sorted[j].StartCol)
+	})
+	return sorted
+}
+
+// find returns the block whose count applies to the statement: the
+// innermost block containing the start of the statement or, if there
+// is none, the first block starting within the statement.
+func (blocks blockList) find(s *StmtExtent) *cover.ProfileBlock {
+	// Blocks [0, n) start at or before the statement.
+	n := sort.Search(len(blocks), func(i int) bool {
+		return positionBefore(s.startLine, s.startCol, blocks[i].StartLine, blocks[i].StartCol)
+	})
+	// Blocks may be nested, so the innermost block containing the
+	// statement's start is the last to start before it.
+	for i := n - 1; i >= 0; i-- {
+		b := &blocks[i]
+		if positionBefore(s.startLine, s.startCol, b.EndLine, b.EndCol) {
+			return b
+		}
+	}
+	if n < len(blocks) {
+		b := &blocks[n]
+		if positionBefore(b.StartLine, b.StartCol, s.endLine, s.endCol) {
+			return b
+		}
+	}
+	return nil
+}
+
+// positionBefore reports whether line1:col1 precedes line2:col2.
+func positionBefore(line1, col1, line2, col2 int) bool {
+	return line1 < line2 || (line1 == line2 && col1 < col2)
+}
+
+// findFuncs parses the file and returns a slice of FuncExtent descriptors,
+// whether the file is generated, and whether it is excluded as a whole by
+// a directive. Functions and statements excluded by directives are omitted.
+func findFuncs(name string) (funcs []*FuncExtent, generated, ignored bool, err error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return nil, false, false, err
+	}
 	fset := token.NewFileSet()
-	parsedFile, err := parser.ParseFile(fset, name, nil, 0)
+	parsedFile, err := parser.ParseFile(fset, name, src, parser.ParseComments)
 	if err != nil {
-		return nil, err
+		return nil, false, false, err
+	}
+	generated = ast.IsGenerated(parsedFile)
+	d := findDirectives(fset, parsedFile, src)
+	if d.ignoreFile {
+		return nil, generated, true, nil
 	}
 	visitor := &FuncVisitor{fset: fset}
 	ast.Walk(visitor, parsedFile)
-	return visitor.funcs, nil
+	return d.filter(visitor.funcs), generated, false, nil
+}
+
+const (
+	// ignoreDirective excludes the function or statement, and anything
+	// within it, starting on the line following the comment group
+	// containing the directive, or if it trails code, on the same line.
+	ignoreDirective = "//gocov:ignore"
+
+	// ignoreFileDirective excludes the whole file.
+	ignoreFileDirective = "//gocov:ignore-file"
+)
+
+// directives holds the coverage directives found in a file.
+type directives struct {
+	ignoreFile bool
+
+	// ignoreLines holds the lines on which functions and statements
+	// starting are ignored.
+	ignoreLines map[int]bool
+}
+
+func findDirectives(fset *token.FileSet, file *ast.File, src []byte) directives {
+	d := directives{ignoreLines: make(map[int]bool)}
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			switch {
+			case isDirective(c.Text, ignoreFileDirective):
+				d.ignoreFile = true
+			case isDirective(c.Text, ignoreDirective):
+				pos := fset.Position(c.Slash)
+				lineStart := pos.Offset - (pos.Column - 1)
+				if len(bytes.TrimSpace(src[lineStart:pos.Offset])) > 0 {
+					d.ignoreLines[pos.Line] = true
+				} else {
+					d.ignoreLines[fset.Position(group.End()).Line+1] = true
+				}
+			}
+		}
+	}
+	return d
+}
+
+// isDirective reports whether the comment text is the given directive,
+// optionally followed by an explanation.
+func isDirective(text, directive string) bool {
+	rest := strings.TrimPrefix(text, directive)
+	return len(rest) < len(text) && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
+
+// filter returns funcs without the functions and statements that are
+// ignored, either directly or by being within an ignored function or
+// statement.
+func (d directives) filter(funcs []*FuncExtent) []*FuncExtent {
+	if len(d.ignoreLines) == 0 {
+		return funcs
+	}
+	var ignored []extent
+	for _, fe := range funcs {
+		if d.ignoreLines[fe.startLine] {
+			ignored = append(ignored, fe.extent)
+		}
+		for _, se := range fe.stmts {
+			if d.ignoreLines[se.startLine] {
+				ignored = append(ignored, extent(*se))
+			}
+		}
+	}
+	within := func(e extent) bool {
+		for _, i := range ignored {
+			if i.startOffset <= e.startOffset && e.endOffset <= i.endOffset {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []*FuncExtent
+	for _, fe := range funcs {
+		if within(fe.extent) {
+			continue
+		}
+		stmts := fe.stmts[:0]
+		for _, se := range fe.stmts {
+			if !within(extent(*se)) {
+				stmts = append(stmts, se)
+			}
+		}
+		fe.stmts = stmts
+		result = append(result, fe)
+	}
+	return result
 }
 
 type extent struct {
@@ -265,12 +603,25 @@ func (v *FuncVisitor) Visit(node ast.Node) ast.Visitor {
 	return v
 }
 
+// StmtVisitor walks the statements of a function body as the cover
+// tool does, recording their extents in the function.
 type StmtVisitor struct {
 	fset     *token.FileSet
 	function *FuncExtent
+
+	// Stmt, if non-nil, is called with each statement visited,
+	// including blocks and the clauses of switch and select
+	// statements, before the statements within it. A StmtVisitor
+	// with only Stmt set records no extents, so may be used to walk
+	// the statements that gocov reports, as the branch coverage
+	// instrumentation does.
+	Stmt func(ast.Stmt)
 }
 
 func (v *StmtVisitor) VisitStmt(s ast.Stmt) {
+	if v.Stmt != nil {
+		v.Stmt(s)
+	}
 	var statements *[]ast.Stmt
 	switch s := s.(type) {
 	case *ast.BlockStmt:
@@ -337,6 +688,9 @@ func (v *StmtVisitor) VisitStmt(s ast.Stmt) {
 		case *ast.CaseClause, *ast.CommClause, *ast.BlockStmt:
 			break
 		default:
+			if v.function == nil {
+				break
+			}
 			start, end := v.fset.Position(s.Pos()), v.fset.Position(s.End())
 			se := &StmtExtent{
 				startOffset: start.Offset,
@@ -1,12 +1,17 @@
 package convert
 
 import (
+	"encoding/json"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/axw/gocov"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
 )
 
 func TestExprName(t *testing.T) {
@@ -38,3 +43,310 @@ func (x *Foo[T]) GenericMethod() {}
 	assert.Equal(t, "Foo[T].GenericMethod", functionName(function3))
 
 }
+
+func TestConvertProfilePositions(t *testing.T) {
+	source := `package foo
+
+func Function() {
+	println("a")
+	if true {
+		println("b")
+	}
+}
+`
+	filename := filepath.Join(t.TempDir(), "foo.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := converter{packages: make(map[string]*gocov.Package)}
+	profile := &cover.Profile{FileName: "foo/foo.go", Mode: "set"}
+	if err := c.convertProfile(profile, filename, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := c.packages["foo"].Functions[0]
+	assert.Equal(t, []int{3, 1, 8, 2}, []int{fn.StartLine, fn.StartCol, fn.EndLine, fn.EndCol})
+	var positions [][]int
+	for _, s := range fn.Statements {
+		positions = append(positions, []int{s.StartLine, s.StartCol, s.EndLine, s.EndCol})
+	}
+	assert.Equal(t, [][]int{{4, 2, 4, 14}, {5, 2, 7, 3}, {6, 3, 6, 15}}, positions)
+}
+
+func TestConvertProfilesSamePackage(t *testing.T) {
+	// Both profiles cover the same package, as when written by
+	// "go test -covermode=set -coverpkg" for two test packages.
+	data, err := ConvertProfiles("testdata/coverpkg/a.cov", "testdata/coverpkg/b.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct{ Packages []*gocov.Package }
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, result.Packages, 1) || !assert.Len(t, result.Packages[0].Functions, 1) {
+		return
+	}
+	var reached []int64
+	for _, s := range result.Packages[0].Functions[0].Statements {
+		reached = append(reached, s.Reached)
+	}
+	assert.Equal(t, []int64{1, 1, 1, 1, 0}, reached)
+}
+
+func TestConvertTestProfiles(t *testing.T) {
+	tests := func(data []byte) [][]string {
+		var result struct{ Packages []*gocov.Package }
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatal(err)
+		}
+		var tests [][]string
+		for _, pkg := range result.Packages {
+			for _, fn := range pkg.Functions {
+				for _, s := range fn.Statements {
+					tests = append(tests, s.Tests)
+				}
+			}
+		}
+		return tests
+	}
+
+	data, err := ConvertTestProfiles(map[string]string{
+		"TestA": "testdata/coverpkg/a.cov",
+		"TestB": "testdata/coverpkg/b.cov",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]string{{"TestA", "TestB"}, {"TestA"}, {"TestB"}, {"TestB"}, nil}, tests(data))
+
+	// The coverage of untested profiles is not attributed to a test.
+	data, err = ConvertTestProfiles(map[string]string{"TestA": "testdata/coverpkg/a.cov"}, "testdata/coverpkg/b.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]string{{"TestA"}, {"TestA"}, nil, nil, nil}, tests(data))
+}
+
+// reachedByFunction returns the Reached counts of the statements of
+// each function in pkg.
+func reachedByFunction(pkg *gocov.Package) map[string][]int64 {
+	reached := make(map[string][]int64)
+	for _, fn := range pkg.Functions {
+		counts := []int64{}
+		for _, s := range fn.Statements {
+			counts = append(counts, s.Reached)
+		}
+		reached[fn.Name] = counts
+	}
+	return reached
+}
+
+func TestConvertProfileFuncLits(t *testing.T) {
+	expected := map[string][]int64{
+		"Apply":    {1, 1, 1, 3, 1, 1, 1},
+		"@6:9":     {3, 2},
+		"@14:12":   {0},
+		"Classify": {2, 0, 2, 0},
+	}
+	for _, mode := range []string{"count", "atomic"} {
+		profiles, err := cover.ParseProfiles("testdata/funclit/" + mode + ".cov")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := converter{packages: make(map[string]*gocov.Package)}
+		err = c.convertProfile(profiles[0], "testdata/funclit/funclit.go", "funclit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, reachedByFunction(c.packages["funclit"]), mode)
+	}
+}
+
+func TestConvertProfileUnsortedBlocks(t *testing.T) {
+	profiles, err := cover.ParseProfiles("testdata/funclit/count.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Order the blocks by function, with function literals following
+	// their enclosing function, as they are when decoded from binary
+	// coverage data.
+	p := profiles[0]
+	blocks := p.Blocks
+	p.Blocks = nil
+	for _, i := range []int{0, 3, 4, 5, 7, 1, 2, 6, 8, 9, 10, 11} {
+		p.Blocks = append(p.Blocks, blocks[i])
+	}
+
+	c := converter{packages: make(map[string]*gocov.Package)}
+	if err := c.convertProfile(p, "testdata/funclit/funclit.go", "funclit"); err != nil {
+		t.Fatal(err)
+	}
+	reached := reachedByFunction(c.packages["funclit"])
+	assert.Equal(t, []int64{1, 1, 1, 3, 1, 1, 1}, reached["Apply"])
+	assert.Equal(t, []int64{3, 2}, reached["@6:9"])
+	assert.Equal(t, []int64{0}, reached["@14:12"])
+}
+
+func TestBlockListFind(t *testing.T) {
+	blocks := sortedBlocks([]cover.ProfileBlock{
+		{StartLine: 1, StartCol: 1, EndLine: 9, EndCol: 1, Count: 1},
+		{StartLine: 3, StartCol: 1, EndLine: 5, EndCol: 1, Count: 2},
+		{StartLine: 7, StartCol: 5, EndLine: 8, EndCol: 1, Count: 3},
+	})
+	for _, test := range []struct {
+		stmt  StmtExtent
+		count int
+	}{
+		// Innermost block containing the statement's start.
+		{StmtExtent{startLine: 2, startCol: 1, endLine: 2, endCol: 9}, 1},
+		{StmtExtent{startLine: 4, startCol: 1, endLine: 4, endCol: 9}, 2},
+		{StmtExtent{startLine: 5, startCol: 1, endLine: 5, endCol: 9}, 1},
+		// No block.
+		{StmtExtent{startLine: 10, startCol: 1, endLine: 10, endCol: 9}, -1},
+	} {
+		b := blocks.find(&test.stmt)
+		count := -1
+		if b != nil {
+			count = b.Count
+		}
+		assert.Equal(t, test.count, count, "%+v", test.stmt)
+	}
+
+	// Without a block containing its start, the first block
+	// starting within the statement.
+	outside := sortedBlocks([]cover.ProfileBlock{{StartLine: 2, StartCol: 3, EndLine: 2, EndCol: 9, Count: 4}})
+	b := outside.find(&StmtExtent{startLine: 2, startCol: 1, endLine: 3, endCol: 1})
+	if assert.NotNil(t, b) {
+		assert.Equal(t, 4, b.Count)
+	}
+}
+
+func TestFindFuncsDirectives(t *testing.T) {
+	source := `package foo
+
+// Ignored is not tested.
+//
+//gocov:ignore
+func Ignored() {
+	println("a")
+	f := func() {
+		println("b")
+	}
+	f()
+}
+
+func Function(x int) {
+	println("c")
+	//gocov:ignore debugging only
+	if x > 0 {
+		println("d")
+	}
+	if x < 0 {
+		panic("unreachable") //gocov:ignore
+	}
+	//gocov:ignore
+	g := func() {
+		println("e")
+	}
+	g()
+}
+`
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "foo.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	funcs, _, ignored, err := findFuncs(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmtLines := make(map[string][]int)
+	for _, fe := range funcs {
+		lines := []int{}
+		for _, se := range fe.stmts {
+			lines = append(lines, se.startLine)
+		}
+		stmtLines[fe.name] = lines
+	}
+	assert.Equal(t, map[string][]int{"Function": {15, 20, 27}}, stmtLines)
+	assert.False(t, ignored)
+
+	ignoreFile := "//gocov:ignore-file\n\n" + source
+	if err := os.WriteFile(filename, []byte(ignoreFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	funcs, _, ignored, err = findFuncs(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, funcs)
+	assert.True(t, ignored)
+
+	// No package is created for a file that is ignored as a whole.
+	c := converter{packages: make(map[string]*gocov.Package), config: &Config{}}
+	profile := &cover.Profile{FileName: "foo/foo.go", Mode: "set"}
+	if err := c.convertProfile(profile, filename, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, c.packages)
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, test := range []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"**/mocks/**", "example.com/foo/mocks/bar.go", true},
+		{"**/mocks/**", "/src/foo/mocks/sub/bar.go", true},
+		{"**/mocks/**", "example.com/foo/mocksy/bar.go", false},
+		{"**/*.pb.go", "example.com/foo/bar.pb.go", true},
+		{"**/*.pb.go", "bar.pb.go", true},
+		{"example.com/*/bar.go", "example.com/foo/bar.go", true},
+		{"example.com/*/bar.go", "example.com/foo/baz/bar.go", false},
+		{"example.com/**/bar.go", "example.com/foo/baz/bar.go", true},
+		{"example.com/foo", "example.com/foo/bar.go", false},
+	} {
+		assert.Equal(t, test.match, matchGlob(test.pattern, test.name), "%q %q", test.pattern, test.name)
+	}
+}
+
+func TestConvertProfileGenerated(t *testing.T) {
+	source := `// Code generated by hand. DO NOT EDIT.
+
+package foo
+
+func Function() {
+	println("a")
+}
+`
+	filename := filepath.Join(t.TempDir(), "foo.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile := &cover.Profile{FileName: "foo/foo.go", Mode: "set"}
+	for _, config := range []*Config{{}, {SkipGenerated: true}} {
+		c := converter{packages: make(map[string]*gocov.Package), config: config}
+		if err := c.convertProfile(profile, filename, "foo"); err != nil {
+			t.Fatal(err)
+		}
+		if config.SkipGenerated {
+			assert.Empty(t, c.packages)
+		} else {
+			assert.Len(t, c.packages, 1)
+		}
+	}
+}
+
+func TestConfigExclude(t *testing.T) {
+	config := &Config{Exclude: []string{"**/coverpkg/*.go"}}
+	data, err := config.ConvertProfiles("testdata/coverpkg/a.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct{ Packages []*gocov.Package }
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, result.Packages)
+}
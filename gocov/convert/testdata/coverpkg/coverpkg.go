@@ -0,0 +1,12 @@
+package coverpkg
+
+// Sign returns the sign of x.
+func Sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
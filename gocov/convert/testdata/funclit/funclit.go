@@ -0,0 +1,30 @@
+package funclit
+
+// Apply sums the positive elements of xs.
+func Apply(xs []int) int {
+	sum := 0
+	add := func(x int) {
+		if x > 0 {
+			sum += x
+		}
+	}
+	for _, x := range xs {
+		add(x)
+	}
+	unused := func() int {
+		return -1
+	}
+	_ = unused
+	return sum
+}
+
+// Classify describes the sign of x.
+func Classify(x int) string {
+	switch {
+	case x < 0:
+		return "negative"
+	case x > 0:
+		return "positive"
+	}
+	return "zero"
+}
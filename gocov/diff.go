@@ -0,0 +1,258 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/axw/gocov"
+)
+
+var (
+	diffFlags         = flag.NewFlagSet("diff", flag.ExitOnError)
+	diffToleranceFlag = diffFlags.Float64(
+		"tolerance", 0,
+		"If specified, fail if any function's statement coverage decreased by more than the specified percentage")
+)
+
+// functionDelta describes the change in coverage of a function between
+// two coverage documents.
+type functionDelta struct {
+	pkg string
+
+	// old and new are the function in each document. old is nil if
+	// the function was added, and new if it was removed.
+	old, new *gocov.Function
+
+	// unreached holds the statements of the new function that are
+	// no longer reached.
+	unreached []*gocov.Statement
+}
+
+// function returns the new function, or the old one if it was removed.
+func (d *functionDelta) function() *gocov.Function {
+	if d.new != nil {
+		return d.new
+	}
+	return d.old
+}
+
+// change returns the change in the function's statement coverage, in
+// percentage points.
+func (d *functionDelta) change() float64 {
+	return functionPercentage(d.new) - functionPercentage(d.old)
+}
+
+// changed reports whether the function was added, removed, or had its
+// coverage changed.
+func (d *functionDelta) changed() bool {
+	return d.old == nil || d.new == nil || d.change() != 0 || len(d.unreached) > 0
+}
+
+func functionPercentage(fn *gocov.Function) float64 {
+	reached, total := statementCoverage(fn)
+	return percentage(reached, total)
+}
+
+// diffPackages matches the functions of the old and new packages as
+// Reconcile does, and returns the change in coverage of each function,
+// ordered by package, file and position.
+func diffPackages(oldPackages, newPackages []*gocov.Package) []*functionDelta {
+	oldByName := make(map[string]*gocov.Package)
+	for _, pkg := range oldPackages {
+		oldByName[pkg.Name] = pkg
+	}
+	var deltas []*functionDelta
+	matched := make(map[*gocov.Function]bool)
+	for _, newPkg := range newPackages {
+		oldPkg := oldByName[newPkg.Name]
+		var matches map[*gocov.Function]*gocov.Function
+		if oldPkg != nil {
+			matches = oldPkg.MatchFunctions(newPkg)
+		}
+		for _, fn := range newPkg.Functions {
+			d := &functionDelta{pkg: newPkg.Name, old: matches[fn], new: fn}
+			if d.old != nil {
+				matched[d.old] = true
+				stmts := d.old.MatchStatements(fn)
+				for _, s := range fn.Statements {
+					if old := stmts[s]; old != nil && old.Reached > 0 && s.Reached == 0 {
+						d.unreached = append(d.unreached, s)
+					}
+				}
+			}
+			deltas = append(deltas, d)
+		}
+	}
+	for _, oldPkg := range oldPackages {
+		for _, fn := range oldPkg.Functions {
+			if !matched[fn] {
+				deltas = append(deltas, &functionDelta{pkg: oldPkg.Name, old: fn})
+			}
+		}
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		a, b := deltas[i], deltas[j]
+		if a.pkg != b.pkg {
+			return a.pkg < b.pkg
+		}
+		// The documents may come from different checkouts, so
+		// only the base names of the files are comparable.
+		fa, fb := a.function(), b.function()
+		if ba, bb := filepath.Base(fa.File), filepath.Base(fb.File); ba != bb {
+			return ba < bb
+		}
+		return fa.Start < fb.Start
+	})
+	return deltas
+}
+
+// statementPosition describes the position of a statement in the
+// named file, by line and column if they were recorded, or else by
+// byte offset.
+func statementPosition(file string, s *gocov.Statement) string {
+	if s.StartLine > 0 {
+		return fmt.Sprintf("%s:%d.%d,%d.%d", file, s.StartLine, s.StartCol, s.EndLine, s.EndCol)
+	}
+	return fmt.Sprintf("%s:#%d,#%d", file, s.Start, s.End)
+}
+
+// printDiff prints the functions whose coverage changed, with the
+// statements that are no longer reached, followed by the change in
+// total coverage.
+func printDiff(w io.Writer, deltas []*functionDelta) {
+	tw := tabwriter.NewWriter(w, 0, 8, 0, '\t', 0)
+	var oldReached, oldTotal, newReached, newTotal int
+	var changed bool
+	for _, d := range deltas {
+		if d.old != nil {
+			reached, total := statementCoverage(d.old)
+			oldReached += reached
+			oldTotal += total
+		}
+		if d.new != nil {
+			reached, total := statementCoverage(d.new)
+			newReached += reached
+			newTotal += total
+		}
+		if !d.changed() {
+			continue
+		}
+		changed = true
+		fn := d.function()
+		file := filepath.Base(fn.File)
+		fmt.Fprintf(tw, "%s/%s\t %s\t ", d.pkg, file, fn.Name)
+		switch {
+		case d.old == nil:
+			reached, total := statementCoverage(d.new)
+			fmt.Fprintf(tw, "new, %.2f%% (%d/%d)\n", percentage(reached, total), reached, total)
+		case d.new == nil:
+			reached, total := statementCoverage(d.old)
+			fmt.Fprintf(tw, "removed, %.2f%% (%d/%d)\n", percentage(reached, total), reached, total)
+		default:
+			fmt.Fprintf(tw, "%.2f%% -> %.2f%% (%+.2f%%)\n",
+				functionPercentage(d.old), functionPercentage(d.new), d.change())
+		}
+		for _, s := range d.unreached {
+			fmt.Fprintf(tw, "\t %s: no longer reached\n", statementPosition(file, s))
+		}
+	}
+	tw.Flush()
+	if changed {
+		fmt.Fprintln(w)
+	}
+	oldPercent := percentage(oldReached, oldTotal)
+	newPercent := percentage(newReached, newTotal)
+	fmt.Fprintf(w, "Total Coverage: %.2f%% (%d/%d) -> %.2f%% (%d/%d) (%+.2f%%)\n",
+		oldPercent, oldReached, oldTotal,
+		newPercent, newReached, newTotal,
+		newPercent-oldPercent)
+}
+
+// diffRegressions describes the functions whose statement coverage
+// decreased by more than tolerance percentage points. Added and
+// removed functions are not regressions.
+func diffRegressions(deltas []*functionDelta, tolerance float64) []string {
+	var regressions []string
+	for _, d := range deltas {
+		if d.old != nil && d.new != nil && -d.change() > tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s/%s: %.2f%% -> %.2f%%",
+				d.pkg, d.new.Name, functionPercentage(d.old), functionPercentage(d.new)))
+		}
+	}
+	return regressions
+}
+
+// readDiffCoverage reads the named coverage document, accumulating
+// the coverage of packages that appear more than once.
+func readDiffCoverage(filename string) ([]*gocov.Package, error) {
+	packages, err := readCoverage(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := newReport()
+	for _, pkg := range packages {
+		if err := r.addPackage(pkg); err != nil {
+			return nil, fmt.Errorf("%s: cannot merge package %q: %s", filename, pkg.Name, err)
+		}
+	}
+	return r.packages, nil
+}
+
+func diffReport() (rc int) {
+	diffFlags.Parse(flag.Args()[1:])
+	if diffFlags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "expected two coverage files: old and new")
+		return 1
+	}
+	oldPackages, err := readDiffCoverage(diffFlags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	newPackages, err := readDiffCoverage(diffFlags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	deltas := diffPackages(oldPackages, newPackages)
+	printDiff(os.Stdout, deltas)
+
+	if !isFlagSet(diffFlags, "tolerance") {
+		return 0
+	}
+
+	if regressions := diffRegressions(deltas, *diffToleranceFlag); len(regressions) > 0 {
+		fmt.Fprintln(os.Stderr, "coverage regressed:")
+		for _, r := range regressions {
+			fmt.Fprintf(os.Stderr, "\t%s\n", r)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+// diffDocuments returns two coverage documents, from different
+// checkouts, in which functions were added, removed and changed.
+func diffDocuments() (oldPackages, newPackages []*gocov.Package) {
+	oldPackages = []*gocov.Package{
+		{Name: "example.com/a", Functions: []*gocov.Function{
+			testFunction("Same", "/old/a/a.go", 1, 1, 1),
+			testFunction("Drop", "/old/a/a.go", 10, 1, 1, 1, 1),
+			testFunction("Gain", "/old/a/a.go", 20, 0, 0),
+			testFunction("Removed", "/old/a/a.go", 30, 1),
+		}},
+		{Name: "example.com/gone", Functions: []*gocov.Function{
+			testFunction("G", "/old/gone/gone.go", 1, 1),
+		}},
+	}
+	newPackages = []*gocov.Package{
+		{Name: "example.com/a", Functions: []*gocov.Function{
+			testFunction("Same", "/new/a/a.go", 1, 2, 1),
+			testFunction("Drop", "/new/a/a.go", 10, 1, 0, 1, 1),
+			testFunction("Gain", "/new/a/a.go", 20, 1, 0),
+			testFunction("Added", "/new/a/a.go", 30, 0, 1),
+		}},
+		{Name: "example.com/new", Functions: []*gocov.Function{
+			testFunction("N", "/new/new/new.go", 1, 1),
+		}},
+	}
+	return oldPackages, newPackages
+}
+
+func TestDiffPackages(t *testing.T) {
+	deltas := diffPackages(diffDocuments())
+	type result struct {
+		name      string
+		old, new  bool
+		change    float64
+		unreached int
+		changed   bool
+	}
+	var got []result
+	for _, d := range deltas {
+		r := result{
+			name:      d.pkg + "." + d.function().Name,
+			old:       d.old != nil,
+			new:       d.new != nil,
+			unreached: len(d.unreached),
+			changed:   d.changed(),
+		}
+		if r.old && r.new {
+			r.change = d.change()
+		}
+		got = append(got, r)
+	}
+	want := []result{
+		{name: "example.com/a.Same", old: true, new: true},
+		{name: "example.com/a.Drop", old: true, new: true, change: -25, unreached: 1, changed: true},
+		{name: "example.com/a.Gain", old: true, new: true, change: 50, changed: true},
+		{name: "example.com/a.Added", new: true, changed: true},
+		{name: "example.com/a.Removed", old: true, changed: true},
+		{name: "example.com/gone.G", old: true, changed: true},
+		{name: "example.com/new.N", new: true, changed: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%+v\nwant:\n%+v", got, want)
+	}
+	if s := deltas[1].unreached[0]; s.StartLine != 12 {
+		t.Errorf("unreached statement on line %d, want 12", s.StartLine)
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	var buf bytes.Buffer
+	printDiff(&buf, diffPackages(diffDocuments()))
+	checkGolden(t, "diff.txt", buf.Bytes())
+
+	// Without changes, only the total is printed.
+	oldPackages, _ := diffDocuments()
+	buf.Reset()
+	printDiff(&buf, diffPackages(oldPackages, oldPackages))
+	want := "Total Coverage: 80.00% (8/10) -> 80.00% (8/10) (+0.00%)\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDiffRegressions(t *testing.T) {
+	deltas := diffPackages(diffDocuments())
+	tests := []struct {
+		tolerance float64
+		want      []string
+	}{
+		{0, []string{"example.com/a/Drop: 100.00% -> 75.00%"}},
+		{24.99, []string{"example.com/a/Drop: 100.00% -> 75.00%"}},
+		// A decrease of exactly the tolerance is allowed.
+		{25, nil},
+		{50, nil},
+	}
+	for _, test := range tests {
+		if got := diffRegressions(deltas, test.tolerance); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tolerance %v: got %q, want %q", test.tolerance, got, test.want)
+		}
+	}
+}
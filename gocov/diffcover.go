@@ -0,0 +1,239 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocov/internal/unidiff"
+)
+
+var (
+	diffcoverFlags    = flag.NewFlagSet("diffcover", flag.ExitOnError)
+	diffcoverBaseFlag = diffcoverFlags.String(
+		"base", "",
+		"Git revision to compare the working tree against, from its merge base with HEAD")
+	diffcoverDiffFlag = diffcoverFlags.String(
+		"diff", "",
+		"Read a unified diff from the specified file (- for stdin) rather than running git")
+	diffcoverRootFlag = diffcoverFlags.String(
+		"root", "",
+		"Directory to which the paths in the diff are relative (default the root of the git repository, or the current directory)")
+	diffcoverMinFlag = diffcoverFlags.Float64(
+		"min", 0,
+		"Fail if coverage of the changed lines is below the specified percentage")
+)
+
+// diffFileCoverage holds the coverage of the changed lines in a file.
+type diffFileCoverage struct {
+	name      string
+	coverable int
+	uncovered []int
+}
+
+func diffCoverage() (rc int) {
+	diffcoverFlags.Parse(flag.Args()[1:])
+	if *diffcoverDiffFlag == "-" && diffcoverFlags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "missing coverage file")
+		return 1
+	}
+	var diff []byte
+	var err error
+	switch {
+	case *diffcoverDiffFlag == "-":
+		diff, err = io.ReadAll(os.Stdin)
+	case *diffcoverDiffFlag != "":
+		diff, err = os.ReadFile(*diffcoverDiffFlag)
+	case *diffcoverBaseFlag != "":
+		diff, err = gitDiff(*diffcoverBaseFlag)
+	default:
+		fmt.Fprintln(os.Stderr, "one of -base or -diff must be specified")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read diff: %s\n", err)
+		return 1
+	}
+	files, err := unidiff.Parse(bytes.NewReader(diff))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse diff: %s\n", err)
+		return 1
+	}
+	report, err := readReport(diffcoverFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := report.addLines(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	root := *diffcoverRootFlag
+	if root == "" {
+		if root, err = gitRoot(); err != nil {
+			if *diffcoverDiffFlag == "" {
+				fmt.Fprintf(os.Stderr, "failed to find repository root: %s\n", err)
+				return 1
+			}
+			root = "."
+		}
+	}
+
+	results := diffFileCoverages(report, files, root)
+	var coverable, uncovered int
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
+	for _, result := range results {
+		coverable += result.coverable
+		uncovered += len(result.uncovered)
+		covered := result.coverable - len(result.uncovered)
+		fmt.Fprintf(w, "%s\t %.2f%% (%d/%d)", result.name,
+			percentage(covered, result.coverable), covered, result.coverable)
+		if len(result.uncovered) > 0 {
+			fmt.Fprintf(w, "\t missed lines %s", formatLines(result.uncovered))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	if coverable == 0 {
+		fmt.Println("No changed lines with statements")
+		return 0
+	}
+	percent := percentage(coverable-uncovered, coverable)
+	fmt.Printf("Changed Line Coverage: %.2f%% (%d/%d)\n", percent, coverable-uncovered, coverable)
+	if percent < *diffcoverMinFlag {
+		fmt.Fprintf(os.Stderr, "changed line coverage %.2f%% is below %.2f%%\n", percent, *diffcoverMinFlag)
+		return 1
+	}
+	return 0
+}
+
+// gitDiff returns the changes in the working tree since the merge base
+// of base and HEAD, with file names relative to the repository root.
+// The prefixes are given explicitly, overriding any diff.noprefix or
+// diff.mnemonicPrefix configuration, as unidiff.Parse expects "b/".
+func gitDiff(base string) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("git", "diff", "--no-color", "--no-ext-diff", "-U0",
+		"--src-prefix=a/", "--dst-prefix=b/", "--merge-base", base)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// gitRoot returns the root directory of the git repository containing
+// the current directory.
+func gitRoot() (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// diffFileCoverages intersects the lines changed in each file with the
+// statements in the report. A changed line counts if a statement starts
+// on it, and is covered if any such statement was reached. The paths in
+// the diff are relative to root, the root of the repository; they are
+// matched against the whole paths of the files in the report.
+func diffFileCoverages(r *report, files []*unidiff.File, root string) []diffFileCoverage {
+	functions := make(map[string][]*gocov.Function)
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			name := canonicalPath(fn.File)
+			functions[name] = append(functions[name], fn)
+		}
+	}
+	root = canonicalPath(root)
+	var results []diffFileCoverage
+	for _, file := range files {
+		matched := functions[filepath.Join(root, filepath.FromSlash(file.Name))]
+		if len(matched) == 0 {
+			continue
+		}
+		hits, _ := lineHits(matched...)
+		result := diffFileCoverage{name: file.Name}
+		for _, line := range file.Lines {
+			reached, ok := hits[line]
+			if !ok {
+				continue
+			}
+			result.coverable++
+			if reached == 0 {
+				result.uncovered = append(result.uncovered, line)
+			}
+		}
+		if result.coverable > 0 {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].name < results[j].name
+	})
+	return results
+}
+
+// canonicalPath returns the absolute path of name with any symbolic
+// links resolved, so that paths reported by git and by the go tool
+// can be compared. If name does not exist, it is only made absolute.
+func canonicalPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(name); err == nil {
+		name = resolved
+	}
+	return filepath.Clean(name)
+}
+
+// formatLines formats ascending line numbers compactly, collapsing
+// consecutive lines into ranges: "3-5,9".
+func formatLines(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(lines[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
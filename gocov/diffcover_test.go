@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocov/internal/unidiff"
+)
+
+func TestDiffFileCoverages(t *testing.T) {
+	root := t.TempDir()
+	r := testReport(t,
+		&gocov.Package{Name: "example.com/demo", Functions: []*gocov.Function{
+			testFunction("Root", filepath.Join(root, "a.go"), 1, 1, 0),
+			testFunction("main", filepath.Join(root, "main.go"), 1, 1),
+		}},
+		&gocov.Package{Name: "example.com/demo/a", Functions: []*gocov.Function{
+			testFunction("A", filepath.Join(root, "a", "a.go"), 1, 0, 0, 0),
+		}},
+		&gocov.Package{Name: "example.com/demo/cmd/tool", Functions: []*gocov.Function{
+			testFunction("main", filepath.Join(root, "cmd", "tool", "main.go"), 1, 0),
+		}},
+	)
+	files := []*unidiff.File{
+		{Name: "a.go", Lines: []int{2, 3, 4}},
+		{Name: "main.go", Lines: []int{2}},
+		{Name: "other.go", Lines: []int{1}},
+	}
+	expected := []diffFileCoverage{
+		{name: "a.go", coverable: 2, uncovered: []int{3}},
+		{name: "main.go", coverable: 1},
+	}
+	results := diffFileCoverages(r, files, root)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+
+	// The same diff, relative to a subdirectory.
+	expected = []diffFileCoverage{
+		{name: "a.go", coverable: 3, uncovered: []int{2, 3, 4}},
+	}
+	results = diffFileCoverages(r, files, filepath.Join(root, "a"))
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+}
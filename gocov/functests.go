@@ -0,0 +1,62 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// printFunctionTests lists the functions in the report whose names
+// match pattern, if it is non-nil, with the names of the tests that
+// reached them, grouped by package and file. Tests are only recorded
+// by "gocov test -per-test"; functions no test reached are listed
+// with "-". Functions without statements are not listed.
+func printFunctionTests(w io.Writer, r *report, pattern *regexp.Regexp) {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', tabwriter.TabIndent)
+	for _, pkg := range r.packages {
+		printedPackage := false
+		for _, file := range packageFiles(pkg) {
+			for _, fn := range file.functions {
+				if len(fn.Statements) == 0 {
+					continue
+				}
+				if pattern != nil && !pattern.MatchString(fn.Name) {
+					continue
+				}
+				if !printedPackage {
+					fmt.Fprintln(tw, pkg.Name)
+					printedPackage = true
+				}
+				tests := "-"
+				if names := fn.Tests(); len(names) > 0 {
+					tests = strings.Join(names, ", ")
+				}
+				fmt.Fprintf(tw, "\t%s:%d\t%s\t%s\n", filepath.Base(file.name), fn.StartLine, fn.Name, tests)
+			}
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestPrintFunctionTests(t *testing.T) {
+	a := testFunction("A", "/src/p/p.go", 1, 1, 1)
+	a.Statements[0].Tests = []string{"TestA", "TestB"}
+	a.Statements[1].Tests = []string{"TestC"}
+	b := testFunction("T.B", "/src/p/p.go", 10, 1)
+	b.Statements[0].Tests = []string{"TestB"}
+	r := testReport(t, &gocov.Package{Name: "example.com/p", Functions: []*gocov.Function{
+		a, b,
+		testFunction("C", "/src/p/q.go", 1, 0),
+		testFunction("Empty", "/src/p/q.go", 10),
+	}})
+
+	var buf bytes.Buffer
+	printFunctionTests(&buf, r, nil)
+	want := "example.com/p\n" +
+		"\tp.go:1  A   TestA, TestB, TestC\n" +
+		"\tp.go:10 T.B TestB\n" +
+		"\tq.go:1  C   -\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printFunctionTests(&buf, r, regexp.MustCompile(`^T\.`))
+	want = "example.com/p\n" +
+		"\tp.go:10 T.B TestB\n"
+	if buf.String() != want {
+		t.Errorf("with -func: got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printFunctionTests(&buf, r, regexp.MustCompile(`^Missing$`))
+	if buf.Len() != 0 {
+		t.Errorf("packages without matching functions were listed:\n%s", buf.String())
+	}
+}
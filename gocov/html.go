@@ -0,0 +1,268 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/axw/gocov"
+)
+
+var (
+	htmlFlags   = flag.NewFlagSet("html", flag.ExitOnError)
+	htmlDirFlag = htmlFlags.String(
+		"dir", "coverage",
+		"Directory in which to write the HTML report")
+)
+
+type htmlSummary struct {
+	Name    string
+	Link    string
+	Reached int
+	Total   int
+}
+
+func (s htmlSummary) Percent() string {
+	var percent float64
+	if s.Total > 0 {
+		percent = float64(s.Reached) / float64(s.Total) * 100
+	}
+	return fmt.Sprintf("%.2f%%", percent)
+}
+
+type htmlPackage struct {
+	htmlSummary
+	Files []htmlSummary
+}
+
+type htmlIndex struct {
+	Total    htmlSummary
+	Packages []htmlPackage
+}
+
+type htmlFunction struct {
+	htmlSummary
+	Line int
+}
+
+type htmlFile struct {
+	htmlSummary
+	Package   string
+	Index     string
+	Functions []htmlFunction
+	Source    template.HTML
+	Error     string
+}
+
+const htmlStyle = `
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.2em 1em; }
+tr.package td { font-weight: bold; border-top: 1px solid #ccc; }
+tr.file td:first-child { padding-left: 2em; }
+td.percent, td.count { text-align: right; font-family: monospace; }
+pre.source { line-height: 1.3; }
+pre.source .ln { display: inline-block; width: 4em; color: #999; text-align: right; padding-right: 1em; user-select: none; }
+pre.source .hit { background-color: #dfd; }
+pre.source .miss { background-color: #fdd; }
+`
+
+var htmlIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage Report</title>
+<style>` + htmlStyle + `</style>
+</head>
+<body>
+<h1>Coverage Report</h1>
+<p>Total Coverage: {{.Total.Percent}} ({{.Total.Reached}}/{{.Total.Total}})</p>
+<table>
+<tr><th>Package / File</th><th>Coverage</th><th>Statements</th></tr>
+{{range .Packages}}<tr class="package"><td>{{.Name}}</td><td class="percent">{{.Percent}}</td><td class="count">{{.Reached}}/{{.Total}}</td></tr>
+{{range .Files}}<tr class="file"><td><a href="{{.Link}}">{{.Name}}</a></td><td class="percent">{{.Percent}}</td><td class="count">{{.Reached}}/{{.Total}}</td></tr>
+{{end}}{{end}}</table>
+</body>
+</html>
+`))
+
+var htmlFileTemplate = template.Must(template.New("file").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Package}}/{{.Name}}</title>
+<style>` + htmlStyle + `</style>
+</head>
+<body>
+<p><a href="{{.Index}}">Coverage Report</a></p>
+<h1>{{.Package}}/{{.Name}}</h1>
+<p>Coverage: {{.Percent}} ({{.Reached}}/{{.Total}})</p>
+<table>
+<tr><th>Function</th><th>Coverage</th><th>Statements</th></tr>
+{{range .Functions}}<tr><td><a href="#L{{.Line}}">{{.Name}}</a></td><td class="percent">{{.Percent}}</td><td class="count">{{.Reached}}/{{.Total}}</td></tr>
+{{end}}</table>
+{{if .Error}}<p>Source unavailable: {{.Error}}</p>{{else}}<pre class="source">{{.Source}}</pre>{{end}}
+</body>
+</html>
+`))
+
+func htmlReport() (rc int) {
+	htmlFlags.Parse(flag.Args()[1:])
+	report, err := readReport(htmlFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := report.addLines(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := writeHTMLReport(*htmlDirFlag, report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeHTMLReport writes a static HTML site for the report to dir: an
+// index of packages and files, and a page for each file showing its
+// functions and annotated source.
+func writeHTMLReport(dir string, r *report) error {
+	var index htmlIndex
+	index.Total.Name = "Total"
+	for _, pkg := range r.packages {
+		p := htmlPackage{htmlSummary: htmlSummary{Name: pkg.Name}}
+		p.Reached, p.Total = statementCoverage(pkg.Functions...)
+		for _, file := range packageFiles(pkg) {
+			link := path.Join(pkg.Name, filepath.Base(file.name)+".html")
+			page := htmlFile{
+				htmlSummary: htmlSummary{Name: filepath.Base(file.name), Link: link},
+				Package:     pkg.Name,
+				Index:       strings.Repeat("../", strings.Count(link, "/")) + "index.html",
+			}
+			page.Reached, page.Total = statementCoverage(file.functions...)
+			for _, fn := range file.functions {
+				f := htmlFunction{htmlSummary: htmlSummary{Name: fn.Name}, Line: fn.StartLine}
+				f.Reached, f.Total = statementCoverage(fn)
+				page.Functions = append(page.Functions, f)
+			}
+			source, err := annotateHTML(file.name, file.functions)
+			if err != nil {
+				page.Error = err.Error()
+			}
+			page.Source = source
+			if err := writeHTMLPage(filepath.Join(dir, filepath.FromSlash(link)), htmlFileTemplate, page); err != nil {
+				return err
+			}
+			p.Files = append(p.Files, page.htmlSummary)
+		}
+		index.Packages = append(index.Packages, p)
+		index.Total.Reached += p.Reached
+		index.Total.Total += p.Total
+	}
+	return writeHTMLPage(filepath.Join(dir, "index.html"), htmlIndexTemplate, index)
+}
+
+func writeHTMLPage(filename string, t *template.Template, data interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+const (
+	htmlNone = iota
+	htmlHit
+	htmlMiss
+)
+
+// annotateHTML returns the HTML-escaped contents of the named source
+// file, with each line numbered and anchored, and each statement marked
+// as hit or missed. Where statements are nested, the innermost wins.
+func annotateHTML(filename string, functions []*gocov.Function) (template.HTML, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	var statements []*gocov.Statement
+	for _, fn := range functions {
+		statements = append(statements, fn.Statements...)
+	}
+	sort.SliceStable(statements, func(i, j int) bool {
+		return statements[i].End-statements[i].Start > statements[j].End-statements[j].Start
+	})
+	state := make([]byte, len(data))
+	for _, stmt := range statements {
+		if stmt.Start < 0 || stmt.End > len(data) || stmt.Start > stmt.End {
+			return "", fmt.Errorf("statement %d-%d out of range; has the file changed?", stmt.Start, stmt.End)
+		}
+		s := byte(htmlMiss)
+		if stmt.Reached > 0 {
+			s = htmlHit
+		}
+		for i := stmt.Start; i < stmt.End; i++ {
+			state[i] = s
+		}
+	}
+
+	var buf bytes.Buffer
+	offset := 0
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		fmt.Fprintf(&buf, `<span id="L%d"><span class="ln">%d</span>`, i+1, i+1)
+		text := strings.TrimSuffix(line, "\n")
+		for start := 0; start < len(text); {
+			end := start + 1
+			for end < len(text) && state[offset+end] == state[offset+start] {
+				end++
+			}
+			escaped := template.HTMLEscapeString(text[start:end])
+			switch state[offset+start] {
+			case htmlHit:
+				fmt.Fprintf(&buf, `<span class="hit">%s</span>`, escaped)
+			case htmlMiss:
+				fmt.Fprintf(&buf, `<span class="miss">%s</span>`, escaped)
+			default:
+				buf.WriteString(escaped)
+			}
+			start = end
+		}
+		buf.WriteString("</span>\n")
+		offset += len(line)
+	}
+	return template.HTML(buf.String()), nil
+}
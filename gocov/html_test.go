@@ -0,0 +1,94 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestAnnotateHTML(t *testing.T) {
+	source := "package a\n\nfunc F(x int) {\n\tif x < 0 {\n\t\tpanic(x)\n\t}\n}\n"
+	filename := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn := &gocov.Function{Name: "F", File: filename, Start: 11, End: 54}
+	fn.Statements = []*gocov.Statement{
+		{Start: 28, End: 52, Reached: 1},
+		{Start: 41, End: 49},
+	}
+	html, err := annotateHTML(filename, []*gocov.Function{fn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<span id="L1"><span class="ln">1</span>package a</span>
+<span id="L2"><span class="ln">2</span></span>
+<span id="L3"><span class="ln">3</span>func F(x int) {</span>
+<span id="L4"><span class="ln">4</span>	<span class="hit">if x &lt; 0 {</span></span>
+<span id="L5"><span class="ln">5</span><span class="hit">		</span><span class="miss">panic(x)</span></span>
+<span id="L6"><span class="ln">6</span><span class="hit">	}</span></span>
+<span id="L7"><span class="ln">7</span>}</span>
+`
+	if string(html) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, html)
+	}
+
+	fn.Statements[0].End = len(source) + 1
+	if _, err := annotateHTML(filename, []*gocov.Function{fn}); err == nil {
+		t.Error("expected an error for a statement beyond the end of the file")
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeHTMLReport(dir, goldenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range []string{"example.com/a/a.go.html", "example.com/a/b.go.html", "example.com/a/sub/sub.go.html"} {
+		if !strings.Contains(string(index), `href="`+link+`"`) {
+			t.Errorf("index does not link to %s", link)
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+			t.Error(err)
+		}
+	}
+	// The source files do not exist, so each page reports an error
+	// in place of the annotated source.
+	page, err := os.ReadFile(filepath.Join(dir, "example.com", "a", "sub", "sub.go.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `href="../../../index.html"`) {
+		t.Error("page does not link back to the index")
+	}
+	if !strings.Contains(string(page), "Source unavailable:") {
+		t.Error("page does not report the missing source")
+	}
+}
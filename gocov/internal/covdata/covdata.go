@@ -0,0 +1,488 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// The file formats decoded here are described in internal/coverage/defs.go
+// in the Go distribution.
+
+// Package covdata decodes the binary coverage data files written to
+// GOCOVERDIR by binaries built with "go build -cover" (Go 1.20+).
+package covdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+const (
+	metaFilePrefix    = "covmeta"
+	counterFilePrefix = "covcounters"
+
+	metaFileVersion    = 1
+	counterFileVersion = 1
+)
+
+var (
+	metaMagic    = [4]byte{0x00, 0x63, 0x76, 0x6d}
+	counterMagic = [4]byte{0x00, 0x63, 0x77, 0x6d}
+)
+
+// Counter modes, as recorded in the meta-data file header.
+const (
+	modeSet    = 1
+	modeCount  = 2
+	modeAtomic = 3
+)
+
+// Counter granularities, as recorded in the meta-data file header.
+const (
+	granularityPerBlock = 1
+	granularityPerFunc  = 2
+)
+
+// Counter flavors, as recorded in the counter data file header.
+const (
+	flavorRaw     = 1
+	flavorULeb128 = 2
+)
+
+type metaFileHeader struct {
+	Magic        [4]byte
+	Version      uint32
+	TotalLength  uint64
+	Entries      uint64
+	MetaFileHash [16]byte
+	StrTabOffset uint32
+	StrTabLength uint32
+	CMode        uint8
+	CGranularity uint8
+	_            [6]byte
+}
+
+type metaSymbolHeader struct {
+	Length     uint32
+	PkgName    uint32
+	PkgPath    uint32
+	ModulePath uint32
+	MetaHash   [16]byte
+	_          byte
+	_          [3]byte
+	NumFiles   uint32
+	NumFuncs   uint32
+}
+
+const metaSymbolHeaderSize = 44
+
+type counterFileHeader struct {
+	Magic     [4]byte
+	Version   uint32
+	MetaHash  [16]byte
+	CFlavor   uint8
+	BigEndian bool
+	_         [6]byte
+}
+
+type counterSegmentHeader struct {
+	FcnEntries uint64
+	StrTabLen  uint32
+	ArgsLen    uint32
+}
+
+type counterFileFooter struct {
+	Magic       [4]byte
+	_           [4]byte
+	NumSegments uint32
+	_           [4]byte
+}
+
+const counterFileFooterSize = 16
+
+// unit is a coverable unit (block) of a function.
+type unit struct {
+	stLine, stCol uint32
+	enLine, enCol uint32
+	nxStmts       uint32
+}
+
+type function struct {
+	file  string
+	units []unit
+}
+
+type metaPackage struct {
+	path  string
+	funcs []function
+}
+
+type metaFile struct {
+	mode        uint8
+	granularity uint8
+	packages    []metaPackage
+}
+
+// ReadDir decodes the meta-data and counter data files in the given
+// coverage directory, returning the equivalent of the textual profile
+// produced by "go tool covdata textfmt". Counters from multiple runs are
+// combined according to the counter mode: summed for "count" and "atomic",
+// and or'd for "set".
+func ReadDir(dir string) ([]*cover.Profile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	metas := make(map[string]*metaFile)
+	var counterFiles []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, metaFilePrefix+"."):
+			hash := strings.TrimPrefix(name, metaFilePrefix+".")
+			meta, err := readMetaFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			metas[hash] = meta
+		case strings.HasPrefix(name, counterFilePrefix+"."):
+			counterFiles = append(counterFiles, name)
+		}
+	}
+	if len(metas) == 0 {
+		return nil, fmt.Errorf("no coverage meta-data files found in %s", dir)
+	}
+
+	var mode uint8
+	for _, meta := range metas {
+		if mode != 0 && mode != meta.mode {
+			return nil, fmt.Errorf("%s: meta-data files have conflicting counter modes", dir)
+		}
+		mode = meta.mode
+	}
+
+	// Every coverable unit is reported, even those in functions that
+	// were never executed; start out with zero counts.
+	b := newBuilder(mode)
+	for _, meta := range metas {
+		for _, pkg := range meta.packages {
+			for _, fn := range pkg.funcs {
+				for _, u := range fn.units {
+					b.add(fn.file, u, 0)
+				}
+			}
+		}
+	}
+
+	for _, name := range counterFiles {
+		err := readCounterFile(filepath.Join(dir, name), func(hash string, pkgIdx, funcIdx uint32, counters []uint32) error {
+			meta := metas[hash]
+			if meta == nil {
+				return fmt.Errorf("no meta-data file for hash %s", hash)
+			}
+			if int(pkgIdx) >= len(meta.packages) {
+				return fmt.Errorf("invalid package index %d", pkgIdx)
+			}
+			pkg := meta.packages[pkgIdx]
+			if int(funcIdx) >= len(pkg.funcs) {
+				return fmt.Errorf("invalid function index %d in package %s", funcIdx, pkg.path)
+			}
+			fn := pkg.funcs[funcIdx]
+			for i, u := range fn.units {
+				var count uint32
+				switch {
+				case meta.granularity == granularityPerFunc && len(counters) > 0:
+					count = counters[0]
+				case i < len(counters):
+					count = counters[i]
+				}
+				b.add(fn.file, u, int(count))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return b.profiles(), nil
+}
+
+// builder accumulates counts for coverable units into profiles.
+type builder struct {
+	mode  uint8
+	files map[string]map[unit]*cover.ProfileBlock
+}
+
+func newBuilder(mode uint8) *builder {
+	return &builder{
+		mode:  mode,
+		files: make(map[string]map[unit]*cover.ProfileBlock),
+	}
+}
+
+func (b *builder) add(file string, u unit, count int) {
+	blocks := b.files[file]
+	if blocks == nil {
+		blocks = make(map[unit]*cover.ProfileBlock)
+		b.files[file] = blocks
+	}
+	block := blocks[u]
+	if block == nil {
+		block = &cover.ProfileBlock{
+			StartLine: int(u.stLine),
+			StartCol:  int(u.stCol),
+			EndLine:   int(u.enLine),
+			EndCol:    int(u.enCol),
+			NumStmt:   int(u.nxStmts),
+		}
+		blocks[u] = block
+	}
+	if b.mode == modeSet {
+		if count > 0 {
+			block.Count = 1
+		}
+	} else {
+		block.Count += count
+	}
+}
+
+func (b *builder) profiles() []*cover.Profile {
+	var mode string
+	switch b.mode {
+	case modeSet:
+		mode = "set"
+	case modeCount:
+		mode = "count"
+	case modeAtomic:
+		mode = "atomic"
+	}
+	profiles := make([]*cover.Profile, 0, len(b.files))
+	for file, blocks := range b.files {
+		p := &cover.Profile{FileName: file, Mode: mode}
+		for _, block := range blocks {
+			p.Blocks = append(p.Blocks, *block)
+		}
+		sort.Slice(p.Blocks, func(i, j int) bool {
+			bi, bj := p.Blocks[i], p.Blocks[j]
+			return bi.StartLine < bj.StartLine || bi.StartLine == bj.StartLine && bi.StartCol < bj.StartCol
+		})
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].FileName < profiles[j].FileName
+	})
+	return profiles
+}
+
+func readMetaFile(filename string) (*metaFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var hdr metaFileHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+	if hdr.Magic != metaMagic {
+		return nil, fmt.Errorf("not a coverage meta-data file")
+	}
+	if hdr.Version > metaFileVersion {
+		return nil, fmt.Errorf("unsupported meta-data file version %d", hdr.Version)
+	}
+	if hdr.TotalLength > uint64(len(data)) {
+		return nil, fmt.Errorf("truncated meta-data file")
+	}
+
+	meta := &metaFile{mode: hdr.CMode, granularity: hdr.CGranularity}
+	offsets := data[binary.Size(hdr):]
+	// The header is followed by the offset and then the length of
+	// each package's meta-data.
+	if hdr.Entries > uint64(len(offsets))/16 {
+		return nil, fmt.Errorf("truncated meta-data file")
+	}
+	for i := uint64(0); i < hdr.Entries; i++ {
+		off := binary.LittleEndian.Uint64(offsets[8*i:])
+		length := binary.LittleEndian.Uint64(offsets[8*(hdr.Entries+i):])
+		if off > hdr.TotalLength || length > hdr.TotalLength-off {
+			return nil, fmt.Errorf("package %d out of range", i)
+		}
+		pkg, err := readMetaPackage(data[off : off+length])
+		if err != nil {
+			return nil, fmt.Errorf("package %d: %v", i, err)
+		}
+		meta.packages = append(meta.packages, pkg)
+	}
+	return meta, nil
+}
+
+func readMetaPackage(payload []byte) (pkg metaPackage, err error) {
+	defer func() {
+		// The decoder below indexes directly into the payload;
+		// turn out of range accesses into errors.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed package meta-data: %v", r)
+		}
+	}()
+	var hdr metaSymbolHeader
+	if err := binary.Read(bytes.NewReader(payload), binary.LittleEndian, &hdr); err != nil {
+		return pkg, err
+	}
+	r := &reader{b: payload, off: metaSymbolHeaderSize + 4*int(hdr.NumFuncs)}
+	strtab := r.stringTable()
+	pkg.path = strtab[hdr.PkgPath]
+	pkg.funcs = make([]function, hdr.NumFuncs)
+	for i := range pkg.funcs {
+		r.off = int(binary.LittleEndian.Uint32(payload[metaSymbolHeaderSize+4*i:]))
+		numUnits := r.uleb128()
+		r.uleb128() // function name
+		fn := function{file: strtab[r.uleb128()]}
+		fn.units = make([]unit, numUnits)
+		for j := range fn.units {
+			fn.units[j] = unit{
+				stLine:  uint32(r.uleb128()),
+				stCol:   uint32(r.uleb128()),
+				enLine:  uint32(r.uleb128()),
+				enCol:   uint32(r.uleb128()),
+				nxStmts: uint32(r.uleb128()),
+			}
+		}
+		pkg.funcs[i] = fn
+	}
+	return pkg, nil
+}
+
+// readCounterFile decodes a counter data file, calling visit for each
+// function payload in each of the file's segments.
+func readCounterFile(filename string, visit func(metaHash string, pkgIdx, funcIdx uint32, counters []uint32) error) (err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed counter data: %v", r)
+		}
+	}()
+	var hdr counterFileHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	if hdr.Magic != counterMagic {
+		return fmt.Errorf("not a coverage counter data file")
+	}
+	if hdr.Version > counterFileVersion {
+		return fmt.Errorf("unsupported counter data file version %d", hdr.Version)
+	}
+	if len(data) < binary.Size(hdr)+counterFileFooterSize {
+		return io.ErrUnexpectedEOF
+	}
+	var ftr counterFileFooter
+	if err := binary.Read(bytes.NewReader(data[len(data)-counterFileFooterSize:]), binary.LittleEndian, &ftr); err != nil {
+		return err
+	}
+	if ftr.Magic != counterMagic {
+		return fmt.Errorf("invalid counter data file footer")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if hdr.BigEndian {
+		order = binary.BigEndian
+	}
+	r := &reader{b: data, off: binary.Size(hdr)}
+	var u32 func() uint32
+	switch hdr.CFlavor {
+	case flavorULeb128:
+		u32 = func() uint32 { return uint32(r.uleb128()) }
+	case flavorRaw:
+		u32 = func() uint32 {
+			v := order.Uint32(r.b[r.off:])
+			r.off += 4
+			return v
+		}
+	default:
+		return fmt.Errorf("unknown counter flavor %d", hdr.CFlavor)
+	}
+
+	metaHash := hex.EncodeToString(hdr.MetaHash[:])
+	var counters []uint32
+	for seg := uint32(0); seg < ftr.NumSegments; seg++ {
+		var shdr counterSegmentHeader
+		if err := binary.Read(bytes.NewReader(data[r.off:]), binary.LittleEndian, &shdr); err != nil {
+			return err
+		}
+		// Skip the segment's string and args tables, and pad to
+		// a four byte boundary.
+		r.off += binary.Size(shdr) + int(shdr.StrTabLen) + int(shdr.ArgsLen)
+		r.off = (r.off + 3) &^ 3
+		for i := uint64(0); i < shdr.FcnEntries; i++ {
+			n := u32()
+			pkgIdx := u32()
+			funcIdx := u32()
+			counters = counters[:0]
+			for j := uint32(0); j < n; j++ {
+				counters = append(counters, u32())
+			}
+			if err := visit(metaHash, pkgIdx, funcIdx, counters); err != nil {
+				return err
+			}
+		}
+		r.off += counterFileFooterSize
+	}
+	return nil
+}
+
+// reader reads values from a byte slice, panicking if it is exhausted.
+type reader struct {
+	b   []byte
+	off int
+}
+
+func (r *reader) uleb128() uint64 {
+	var value uint64
+	var shift uint
+	for {
+		b := r.b[r.off]
+		r.off++
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value
+		}
+		shift += 7
+	}
+}
+
+func (r *reader) stringTable() []string {
+	n := r.uleb128()
+	strs := make([]string, n)
+	for i := range strs {
+		length := int(r.uleb128())
+		strs[i] = string(r.b[r.off : r.off+length])
+		r.off += length
+	}
+	return strs
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package covdata
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+// The testdata directories were written by a program built with
+// "go build -cover" and run twice; the .txt files are the output of
+// "go tool covdata textfmt" for the same directories.
+func TestReadDir(t *testing.T) {
+	for _, mode := range []string{"count", "set"} {
+		profiles, err := ReadDir(filepath.Join("testdata", mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := cover.ParseProfiles(filepath.Join("testdata", mode+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, profiles, mode)
+	}
+}
+
+func TestReadDirNoMetaData(t *testing.T) {
+	_, err := ReadDir(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestReadMetaFileCorrupt(t *testing.T) {
+	metas, err := filepath.Glob(filepath.Join("testdata", "count", "covmeta.*"))
+	if err != nil || len(metas) != 1 {
+		t.Fatalf("expected one meta-data file, got %v (%v)", metas, err)
+	}
+	data, err := os.ReadFile(metas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hdr metaFileHeader
+	hdrSize := binary.Size(hdr)
+
+	// An entry count that the offsets following the header cannot hold.
+	corrupt := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(corrupt[16:], 1<<40)
+	filename := filepath.Join(t.TempDir(), "covmeta.corrupt")
+	if err := os.WriteFile(filename, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readMetaFile(filename)
+	assert.Error(t, err)
+
+	// A file truncated just after the header, with a total length
+	// to match.
+	truncated := append([]byte(nil), data[:hdrSize+8]...)
+	binary.LittleEndian.PutUint64(truncated[8:], uint64(len(truncated)))
+	if err := os.WriteFile(filename, truncated, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readMetaFile(filename)
+	assert.Error(t, err)
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package instrument rewrites Go source files to record branch
+// coverage, which Go's cover tool does not: the outcomes of the
+// conditions of if and for statements and of the operands of
+// conditional operators within them, and the entry of each clause of
+// switch and select statements. The statements are found by walking
+// each function body with convert.StmtVisitor, as when converting
+// statement coverage.
+//
+// The rewritten files are written to a directory along with an overlay
+// file to pass to "go test -overlay". Calls are only ever inserted
+// within a line, so line numbers are preserved. Each instrumented
+// package gains a generated runtime file which appends the first hit of
+// each outcome to the file named by the GOCOV_BRANCHES environment
+// variable, so that no hook is needed to flush the results at exit.
+package instrument
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocov/convert"
+	"golang.org/x/tools/go/packages"
+)
+
+// EnvVar is the environment variable naming the file to which the
+// instrumented code appends branch outcomes.
+const EnvVar = "GOCOV_BRANCHES"
+
+// runtimeFile is the name of the file added to each instrumented
+// package to define the functions called by the instrumented code.
+const runtimeFile = "gocov_branches.go"
+
+// Overlay describes the instrumented source files of a set of packages.
+type Overlay struct {
+	// File is the path to the overlay file to pass to "go test -overlay".
+	File string
+
+	// branches holds the branch points of all instrumented files,
+	// indexed by their ID.
+	branches []*branch
+}
+
+// branch is a branch point in an instrumented file of the package
+// with import path pkg.
+type branch struct {
+	pkg, file string
+	*gocov.Branch
+}
+
+// Instrument loads the packages matching patterns, and writes
+// instrumented copies of their Go files, and an overlay file
+// replacing the originals with them, to dir. Test files and files
+// using cgo are not instrumented.
+func Instrument(dir string, patterns ...string) (*Overlay, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles,
+	}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %v", err)
+	}
+
+	o := &Overlay{File: filepath.Join(dir, "overlay.json")}
+	replace := make(map[string]string)
+	for i, pkg := range pkgs {
+		if pkg.Name == "" || len(pkg.GoFiles) == 0 {
+			continue
+		}
+		base := len(o.branches)
+		// Files excluded by build constraints are instrumented too,
+		// in case the tests are built with different tags, but as they
+		// may not be intended to build at all, errors are ignored.
+		files := append(append([]string(nil), pkg.GoFiles...), pkg.IgnoredFiles...)
+		for j, filename := range files {
+			if !strings.HasSuffix(filename, ".go") || strings.HasSuffix(filename, "_test.go") {
+				continue
+			}
+			src, branches, err := instrumentFile(filename, pkg.Name, len(o.branches))
+			if err != nil {
+				if j >= len(pkg.GoFiles) {
+					continue
+				}
+				return nil, err
+			}
+			if len(branches) == 0 {
+				continue
+			}
+			instrumented := filepath.Join(dir, fmt.Sprintf("%d_%s", i, filepath.Base(filename)))
+			if err := os.WriteFile(instrumented, src, 0644); err != nil {
+				return nil, err
+			}
+			replace[filename] = instrumented
+			for _, b := range branches {
+				o.branches = append(o.branches, &branch{pkg: pkg.PkgPath, file: filename, Branch: b})
+			}
+		}
+		if len(o.branches) == base {
+			continue
+		}
+
+		pkgDir := filepath.Dir(pkg.GoFiles[0])
+		if _, err := os.Stat(filepath.Join(pkgDir, runtimeFile)); err == nil {
+			return nil, fmt.Errorf("%s: cannot add %s, file exists", pkg.PkgPath, runtimeFile)
+		}
+		var buf bytes.Buffer
+		err := runtimeTemplate.Execute(&buf, struct {
+			Package     string
+			Base, Count int
+		}{pkg.Name, base, len(o.branches) - base})
+		if err != nil {
+			return nil, err
+		}
+		runtime := filepath.Join(dir, fmt.Sprintf("%d_%s", i, runtimeFile))
+		if err := os.WriteFile(runtime, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		replace[filepath.Join(pkgDir, runtimeFile)] = runtime
+	}
+
+	data, err := json.Marshal(struct{ Replace map[string]string }{replace})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(o.File, data, 0644); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// insertion is text to insert into a source file before the byte at
+// offset.
+type insertion struct {
+	offset int
+	text   string
+}
+
+// instrumentFile returns the source of the named file with its branch
+// points instrumented, and the branch points, whose IDs start at base.
+// Files belonging to another package, and files using cgo, are left
+// unchanged.
+func instrumentFile(filename, pkgName string, base int) ([]byte, []*gocov.Branch, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.Name.Name != pkgName {
+		return nil, nil, nil
+	}
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "C" {
+			return nil, nil, nil
+		}
+	}
+
+	v := &visitor{fset: fset, base: base}
+	ast.Inspect(file, func(node ast.Node) bool {
+		var body *ast.BlockStmt
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			body = n.Body
+		case *ast.FuncLit:
+			body = n.Body
+		}
+		if body != nil {
+			// Function literals are walked separately, as the
+			// visitor does not descend into expressions.
+			sv := convert.StmtVisitor{Stmt: v.visitStmt}
+			sv.VisitStmt(body)
+		}
+		return true
+	})
+	if len(v.branches) == 0 {
+		return nil, nil, nil
+	}
+
+	// Insertions at the same offset are made in the order they were
+	// added, so that enclosing calls are opened first.
+	sort.SliceStable(v.insertions, func(i, j int) bool {
+		return v.insertions[i].offset < v.insertions[j].offset
+	})
+	var buf bytes.Buffer
+	last := 0
+	for _, ins := range v.insertions {
+		buf.Write(src[last:ins.offset])
+		buf.WriteString(ins.text)
+		last = ins.offset
+	}
+	buf.Write(src[last:])
+	return buf.Bytes(), v.branches, nil
+}
+
+// visitor finds the branch points in a file, and the insertions that
+// instrument them.
+type visitor struct {
+	fset       *token.FileSet
+	base       int
+	branches   []*gocov.Branch
+	insertions []insertion
+}
+
+// visitStmt instruments the branch points of a statement.
+func (v *visitor) visitStmt(stmt ast.Stmt) {
+	switch n := stmt.(type) {
+	case *ast.IfStmt:
+		v.condition("if", n.Cond)
+	case *ast.ForStmt:
+		if n.Cond != nil {
+			v.condition("for", n.Cond)
+		}
+	case *ast.CaseClause:
+		kind := "case"
+		if n.List == nil {
+			kind = "default"
+		}
+		v.clause(kind, n.Case, n.Colon)
+	case *ast.CommClause:
+		kind := "case"
+		if n.Comm == nil {
+			kind = "default"
+		}
+		v.clause(kind, n.Case, n.Colon)
+	}
+}
+
+// add records a branch point with the given number of outcomes,
+// returning its ID.
+func (v *visitor) add(kind string, pos, end token.Pos, outcomes int) int {
+	start, stop := v.fset.PositionFor(pos, false), v.fset.PositionFor(end, false)
+	v.branches = append(v.branches, &gocov.Branch{
+		Kind:      kind,
+		Start:     start.Offset,
+		End:       stop.Offset,
+		StartLine: start.Line,
+		StartCol:  start.Column,
+		EndLine:   stop.Line,
+		EndCol:    stop.Column,
+		Taken:     make([]bool, outcomes),
+	})
+	return v.base + len(v.branches) - 1
+}
+
+func (v *visitor) insert(pos token.Pos, text string) {
+	offset := v.fset.PositionFor(pos, false).Offset
+	v.insertions = append(v.insertions, insertion{offset, text})
+}
+
+// condition instruments the condition of an if or for statement, and
+// the operands of any conditional operators within it.
+func (v *visitor) condition(kind string, cond ast.Expr) {
+	v.wrap(kind, cond)
+	v.operands(cond)
+}
+
+// operands instruments the operands of x, if it is a conditional
+// operator, descending into operands which are themselves conditional
+// operators.
+func (v *visitor) operands(x ast.Expr) {
+	op, ok := unparen(x).(*ast.BinaryExpr)
+	if !ok || (op.Op != token.LAND && op.Op != token.LOR) {
+		return
+	}
+	for _, y := range []ast.Expr{op.X, op.Y} {
+		if inner, ok := unparen(y).(*ast.BinaryExpr); ok && (inner.Op == token.LAND || inner.Op == token.LOR) {
+			v.operands(inner)
+		} else {
+			v.wrap(op.Op.String(), y)
+		}
+	}
+}
+
+// wrap instruments the boolean expression x, recording its outcome
+// each time it is evaluated.
+func (v *visitor) wrap(kind string, x ast.Expr) {
+	id := v.add(kind, x.Pos(), x.End(), 2)
+	v.insert(x.Pos(), fmt.Sprintf("gocovB(%d, bool(", id))
+	v.insert(x.End(), "))")
+}
+
+// clause instruments a case clause, recording its entry.
+func (v *visitor) clause(kind string, pos, colon token.Pos) {
+	id := v.add(kind, pos, colon+1, 1)
+	v.insert(colon+1, fmt.Sprintf(" gocovC(%d);", id))
+}
+
+func unparen(x ast.Expr) ast.Expr {
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
+
+var runtimeTemplate = template.Must(template.New("runtime").Parse(`// Code generated by gocov. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	gocovos "os"
+	gocovstrconv "strconv"
+	gocovatomic "sync/atomic"
+)
+
+var gocovHits [{{.Count}}][2]uint32
+
+func gocovB(id int, cond bool) bool {
+	if cond {
+		gocovHit(id, 0)
+	} else {
+		gocovHit(id, 1)
+	}
+	return cond
+}
+
+func gocovC(id int) {
+	gocovHit(id, 0)
+}
+
+// gocovHit appends the first hit of each outcome to the file named
+// by $` + EnvVar + `.
+func gocovHit(id, outcome int) {
+	hit := &gocovHits[id-{{.Base}}][outcome]
+	if gocovatomic.LoadUint32(hit) != 0 || !gocovatomic.CompareAndSwapUint32(hit, 0, 1) {
+		return
+	}
+	f, err := gocovos.OpenFile(gocovos.Getenv("` + EnvVar + `"), gocovos.O_WRONLY|gocovos.O_APPEND|gocovos.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	f.Write([]byte(gocovstrconv.Itoa(id) + " " + gocovstrconv.Itoa(outcome) + "\n"))
+	f.Close()
+}
+`))
+
+// Apply adds the branch points, with the outcomes read by
+// ReadOutcomes, to the functions in packages that contain them.
+func (o *Overlay) Apply(pkgs []*gocov.Package) {
+	functions := make(map[string][]*gocov.Function)
+	for _, pkg := range pkgs {
+		for _, fn := range pkg.Functions {
+			functions[fn.File] = append(functions[fn.File], fn)
+			fn.Branches = nil
+		}
+	}
+	for _, b := range o.branches {
+		// Function literals are separate functions, so attribute the
+		// branch point to the innermost function containing it.
+		var enclosing *gocov.Function
+		for _, fn := range functions[b.file] {
+			if fn.Start <= b.Start && b.End <= fn.End && (enclosing == nil || fn.Start > enclosing.Start) {
+				enclosing = fn
+			}
+		}
+		if enclosing != nil {
+			enclosing.Branches = append(enclosing.Branches, b.Branch)
+		}
+	}
+	for _, fns := range functions {
+		for _, fn := range fns {
+			sort.SliceStable(fn.Branches, func(i, j int) bool {
+				return fn.Branches[i].Start < fn.Branches[j].Start
+			})
+		}
+	}
+}
+
+// ReadOutcomes reads the "id outcome" lines appended to the named file
+// by the instrumented code, marking the outcomes as taken. If pkgPath
+// is non-empty, only the outcomes of branch points in that package are
+// marked: like statement coverage without -coverpkg, the branch
+// coverage of a package is that recorded by its own tests. A missing
+// file is treated as recording no outcomes.
+func (o *Overlay) ReadOutcomes(filename, pkgPath string) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var id, outcome int
+		if _, err := fmt.Sscan(s.Text(), &id, &outcome); err != nil {
+			return fmt.Errorf("%s: invalid branch outcome %q", filename, s.Text())
+		}
+		if id < 0 || id >= len(o.branches) || outcome < 0 || outcome >= len(o.branches[id].Taken) {
+			return fmt.Errorf("%s: unknown branch outcome %q", filename, s.Text())
+		}
+		if pkgPath == "" || o.branches[id].pkg == pkgPath {
+			o.branches[id].Taken[outcome] = true
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package instrument
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axw/gocov"
+	"github.com/stretchr/testify/assert"
+)
+
+const source = `package p
+
+func F(x, y int) int {
+	if x > 0 && (y > 0 || x > 10) {
+		return 1
+	}
+	for i := 0; i < x; i++ {
+	}
+	switch x {
+	case 1, 2:
+	default:
+		return 2
+	}
+	return 0
+}
+`
+
+const instrumented = `package p
+
+func F(x, y int) int {
+	if gocovB(0, bool(gocovB(1, bool(x > 0)) && (gocovB(2, bool(y > 0)) || gocovB(3, bool(x > 10))))) {
+		return 1
+	}
+	for i := 0; gocovB(4, bool(i < x)); i++ {
+	}
+	switch x {
+	case 1, 2: gocovC(5);
+	default: gocovC(6);
+		return 2
+	}
+	return 0
+}
+`
+
+func writeSource(t *testing.T) string {
+	filename := filepath.Join(t.TempDir(), "p.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestInstrumentFile(t *testing.T) {
+	src, branches, err := instrumentFile(writeSource(t), "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, instrumented, string(src))
+	assert.Equal(t, strings.Count(source, "\n"), strings.Count(string(src), "\n"))
+
+	var kinds []string
+	for _, b := range branches {
+		kinds = append(kinds, b.Kind)
+	}
+	assert.Equal(t, []string{"if", "&&", "||", "||", "for", "case", "default"}, kinds)
+	assert.Equal(t, []int{4, 5, 4, 31}, []int{branches[0].StartLine, branches[0].StartCol, branches[0].EndLine, branches[0].EndCol})
+	assert.Equal(t, []int{10, 2, 10, 12}, []int{branches[5].StartLine, branches[5].StartCol, branches[5].EndLine, branches[5].EndCol})
+	assert.Len(t, branches[0].Taken, 2)
+	assert.Len(t, branches[5].Taken, 1)
+}
+
+func TestInstrumentFileOtherPackage(t *testing.T) {
+	src, branches, err := instrumentFile(writeSource(t), "q", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, src)
+	assert.Nil(t, branches)
+}
+
+func TestInstrumentFileFuncLits(t *testing.T) {
+	const source = `package p
+
+var f = func(x int) bool {
+	if x > 0 {
+		return true
+	} else if x < -1 {
+		return g(func() bool { return x > 1 })
+	}
+	return false
+}
+
+func g(h func() bool) bool {
+	select {
+	default:
+	}
+	return h()
+}
+`
+	filename := filepath.Join(t.TempDir(), "p.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, branches, err := instrumentFile(filename, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	var lines []int
+	for _, b := range branches {
+		kinds = append(kinds, b.Kind)
+		lines = append(lines, b.StartLine)
+	}
+	assert.Equal(t, []string{"if", "if", "default"}, kinds)
+	assert.Equal(t, []int{4, 6, 14}, lines)
+}
+
+func TestApply(t *testing.T) {
+	filename := writeSource(t)
+	_, branches, err := instrumentFile(filename, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &Overlay{}
+	for _, b := range branches {
+		o.branches = append(o.branches, &branch{pkg: "example.com/p", file: filename, Branch: b})
+	}
+	outcomes := filepath.Join(t.TempDir(), "branches.out")
+	if err := os.WriteFile(outcomes, []byte("0 1\n1 0\n1 1\n6 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ReadOutcomes(outcomes, "example.com/p"); err != nil {
+		t.Fatal(err)
+	}
+	// A missing file records no outcomes.
+	if err := o.ReadOutcomes(outcomes+".missing", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := &gocov.Function{Name: "F", File: filename, Start: 11, End: len(source) - 1}
+	pkgs := []*gocov.Package{{Name: "example.com/p", Functions: []*gocov.Function{fn}}}
+	o.Apply(pkgs)
+	var taken [][]bool
+	for _, b := range fn.Branches {
+		taken = append(taken, b.Taken)
+	}
+	assert.Equal(t, [][]bool{
+		{false, true}, {true, true}, {false, false}, {false, false},
+		{false, false}, {false}, {true},
+	}, taken)
+
+	if err := os.WriteFile(outcomes, []byte("7 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, o.ReadOutcomes(outcomes, ""))
+}
+
+func TestReadOutcomesPackage(t *testing.T) {
+	o := &Overlay{branches: []*branch{
+		{pkg: "example.com/a", Branch: &gocov.Branch{Taken: make([]bool, 2)}},
+		{pkg: "example.com/b", Branch: &gocov.Branch{Taken: make([]bool, 2)}},
+	}}
+	outcomes := filepath.Join(t.TempDir(), "branches.out")
+	if err := os.WriteFile(outcomes, []byte("0 0\n1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Outcomes recorded by the tests of b in a are not counted.
+	if err := o.ReadOutcomes(outcomes, "example.com/b"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]bool{{false, false}, {false, true}}, [][]bool{o.branches[0].Taken, o.branches[1].Taken})
+
+	if err := o.ReadOutcomes(outcomes, ""); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]bool{{true, false}, {false, true}}, [][]bool{o.branches[0].Taken, o.branches[1].Taken})
+}
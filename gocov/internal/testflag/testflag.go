@@ -37,37 +37,74 @@ type testFlagSpec struct {
 var testFlagDefn = []*testFlagSpec{
 	// test-specific
 	{name: "i", isBool: true},
+	{name: "artifacts", isBool: true},
 	{name: "bench"},
 	{name: "benchmem", isBool: true},
 	{name: "benchtime"},
+	{name: "count"},
+	{name: "cover", isBool: true},
 	{name: "covermode"},
+	{name: "coverpkg"},
+	{name: "coverprofile"},
 	{name: "cpu"},
 	{name: "cpuprofile"},
+	{name: "failfast", isBool: true},
+	{name: "fullpath", isBool: true},
+	{name: "fuzz"},
+	{name: "fuzzminimizetime"},
+	{name: "fuzztime"},
+	{name: "list"},
 	{name: "memprofile"},
 	{name: "memprofilerate"},
 	{name: "blockprofile"},
 	{name: "blockprofilerate"},
+	{name: "mutexprofile"},
+	{name: "mutexprofilefraction"},
+	{name: "outputdir"},
 	{name: "parallel"},
 	{name: "run"},
 	{name: "short", isBool: true},
+	{name: "shuffle"},
+	{name: "skip"},
 	{name: "timeout"},
 	{name: "trace"},
 	{name: "v", isBool: true},
+	{name: "vet"},
 
 	// common build flags
+	{name: "C"},
 	{name: "a", isBool: true},
+	{name: "n", isBool: true},
+	{name: "p"},
 	{name: "race", isBool: true},
+	{name: "msan", isBool: true},
+	{name: "asan", isBool: true},
+	{name: "work", isBool: true},
 	{name: "x", isBool: true},
 	{name: "asmflags"},
 	{name: "buildmode"},
+	{name: "buildvcs", isBool: true},
 	{name: "compiler"},
 	{name: "gccgoflags"},
 	{name: "gcflags"},
+	{name: "installsuffix"},
+	{name: "json", isBool: true},
 	{name: "ldflags"},
 	{name: "linkshared", isBool: true},
+	{name: "mod"},
+	{name: "modcacherw", isBool: true},
+	{name: "modfile"},
+	{name: "overlay"},
+	{name: "pgo"},
 	{name: "pkgdir"},
 	{name: "tags"},
 	{name: "toolexec"},
+	{name: "trimpath", isBool: true},
+
+	// "go test" flags
+	{name: "c", isBool: true},
+	{name: "exec"},
+	{name: "o"},
 }
 
 // Split processes the arguments , separating flags and package
@@ -91,6 +128,13 @@ func Split(args []string) (packageNames, passToTest []string) {
 			inPkg = false
 		}
 
+		if args[i] == "-args" || args[i] == "--args" {
+			// The remaining arguments are passed to the test binary
+			// untouched, even if they look like flags or packages.
+			passToTest = append(passToTest, args[i:]...)
+			break
+		}
+
 		n := parseTestFlag(args, i)
 		if n == 0 {
 			// This is a flag we do not know; we must assume
@@ -111,6 +155,47 @@ func Split(args []string) (packageNames, passToTest []string) {
 	return packageNames, passToTest
 }
 
+// Remove returns args, as returned by Split, without the named flags
+// and their values. Arguments from -args onwards are kept.
+func Remove(args []string, names ...string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-args" || args[i] == "--args" {
+			return append(kept, args[i:]...)
+		}
+		n := parseTestFlag(args, i)
+		if n == 0 {
+			kept = append(kept, args[i])
+			continue
+		}
+		if i+n > len(args) {
+			n = len(args) - i
+		}
+		remove := false
+		for _, name := range names {
+			if flagName(args[i]) == name {
+				remove = true
+			}
+		}
+		if !remove {
+			kept = append(kept, args[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return kept
+}
+
+// flagName returns the name of the flag in arg, without any leading
+// minuses, "test." prefix or value.
+func flagName(arg string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	name = strings.TrimPrefix(name, "test.")
+	if equals := strings.Index(name, "="); equals >= 0 {
+		name = name[:equals]
+	}
+	return name
+}
+
 // parseTestFlag sees if argument i is a known flag and returns its
 // definition, value, and whether it consumed an extra word.
 func parseTestFlag(args []string, i int) (n int) {
@@ -125,17 +210,11 @@ func parseTestFlag(args []string, i int) (n int) {
 	if arg == "" || arg[0] != '-' {
 		return 0
 	}
-	name := arg[1:]
-	// If there's already "test.", drop it for now.
-	name = strings.TrimPrefix(name, "test.")
-	equals := strings.Index(name, "=")
-	if equals >= 0 {
-		name = name[:equals]
-	}
+	name := flagName(arg)
 	for _, f := range testFlagDefn {
 		if name == f.name {
 			// Booleans are special because they have modes -x, -x=true, -x=false.
-			if !f.isBool && equals < 0 {
+			if !f.isBool && !strings.Contains(arg, "=") {
 				return 2
 			}
 			return 1
@@ -21,7 +21,9 @@
 package testflag
 
 import (
+	"os/exec"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -63,6 +65,26 @@ var tests = []test{{
 	input:        []string{"-h", "-?", "-help"},
 	packageNames: nil,
 	passToTest:   []string{"-h", "-?", "-help"},
+}, {
+	input:        []string{"-coverpkg", "./...", "./x", "./y"},
+	packageNames: []string{"./x", "./y"},
+	passToTest:   []string{"-coverpkg", "./..."},
+}, {
+	input:        []string{"-count", "1", "-failfast", "-shuffle", "on", "-json", "./..."},
+	packageNames: []string{"./..."},
+	passToTest:   []string{"-count", "1", "-failfast", "-shuffle", "on", "-json"},
+}, {
+	input:        []string{"-mod=vendor", "-overlay", "o.json", "-trimpath", "./x"},
+	packageNames: []string{"./x"},
+	passToTest:   []string{"-mod=vendor", "-overlay", "o.json", "-trimpath"},
+}, {
+	input:        []string{"./x", "-args", "-tags", "y", "z"},
+	packageNames: []string{"./x"},
+	passToTest:   []string{"-args", "-tags", "y", "z"},
+}, {
+	input:        []string{"-v", "--args", "./y"},
+	packageNames: nil,
+	passToTest:   []string{"-v", "--args", "./y"},
 }, {
 	input:        []string{"--v", "--tags=a b c", "pkgname"},
 	packageNames: []string{"pkgname"},
@@ -81,3 +103,47 @@ func TestSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestRemove(t *testing.T) {
+	args := []string{"-v", "-coverpkg", "./...", "-covermode=set", "-count", "1", "-cover", "-unknown", "-args", "-cover"}
+	kept := Remove(args, "cover", "covermode", "coverpkg")
+	expected := []string{"-v", "-count", "1", "-unknown", "-args", "-cover"}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Remove: %q != %q", kept, expected)
+	}
+}
+
+// helpFlagRE matches the flags documented by "go help", capturing the
+// flag name and the placeholder for its value, if any.
+var helpFlagRE = regexp.MustCompile(`(?m)^\t-([a-zA-Z][a-zA-Z0-9]*)( \S.*)?$`)
+
+func TestFlagDefnMatchesToolchain(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	defns := make(map[string]*testFlagSpec)
+	for _, f := range testFlagDefn {
+		defns[f.name] = f
+	}
+	for _, topic := range []string{"testflag", "build", "test"} {
+		out, err := exec.Command("go", "help", topic).Output()
+		if err != nil {
+			t.Fatalf("go help %s: %v", topic, err)
+		}
+		for _, m := range helpFlagRE.FindAllStringSubmatch(string(out), -1) {
+			name, isBool := m[1], m[2] == ""
+			if name == "args" {
+				// Handled specially by Split.
+				continue
+			}
+			f, ok := defns[name]
+			if !ok {
+				t.Errorf("flag -%s from \"go help %s\" is missing", name, topic)
+				continue
+			}
+			if f.isBool != isBool {
+				t.Errorf("flag -%s from \"go help %s\": isBool is %v, want %v", name, topic, f.isBool, isBool)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package unidiff extracts the lines added by a unified diff, such as
+// the output of "git diff".
+package unidiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// File describes the lines added to a file by a diff.
+type File struct {
+	// Name is the path of the file after the change, as given in
+	// the diff, without any "b/" prefix.
+	Name string
+
+	// Lines holds the line numbers in the new file of the added
+	// lines, in ascending order.
+	Lines []int
+}
+
+// Parse parses a unified diff, returning the files with added lines in
+// the order they appear. Deleted files are omitted.
+func Parse(r io.Reader) ([]*File, error) {
+	var files []*File
+	var file *File
+	var oldRemaining, newRemaining, line int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		text := scanner.Text()
+		if oldRemaining > 0 || newRemaining > 0 {
+			// Within a hunk.
+			switch {
+			case strings.HasPrefix(text, "+"):
+				if file != nil {
+					file.Lines = append(file.Lines, line)
+				}
+				line++
+				newRemaining--
+			case strings.HasPrefix(text, "-"):
+				oldRemaining--
+			case strings.HasPrefix(text, "\\"):
+				// "\ No newline at end of file"
+			default:
+				line++
+				oldRemaining--
+				newRemaining--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			name := strings.TrimPrefix(text, "+++ ")
+			if i := strings.IndexByte(name, '\t'); i >= 0 {
+				// Strip any timestamp.
+				name = name[:i]
+			}
+			if name == "/dev/null" {
+				file = nil
+				continue
+			}
+			file = &File{Name: strings.TrimPrefix(name, "b/")}
+			files = append(files, file)
+		case strings.HasPrefix(text, "@@ "):
+			var err error
+			var newStart int
+			oldRemaining, newStart, newRemaining, err = parseHunkHeader(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			line = newStart
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var nonEmpty []*File
+	for _, file := range files {
+		if len(file.Lines) > 0 {
+			nonEmpty = append(nonEmpty, file)
+		}
+	}
+	return nonEmpty, nil
+}
+
+// parseHunkHeader parses a hunk header of the form
+// "@@ -l[,s] +l[,s] @@", returning the number of lines in the hunk
+// from the old file, and the start and number of lines in the hunk
+// from the new file.
+func parseHunkHeader(text string) (oldLines, newStart, newLines int, err error) {
+	fields := strings.Fields(text)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("malformed hunk header %q", text)
+	}
+	if _, oldLines, err = parseRange(fields[1][1:]); err != nil {
+		return 0, 0, 0, err
+	}
+	if newStart, newLines, err = parseRange(fields[2][1:]); err != nil {
+		return 0, 0, 0, err
+	}
+	return oldLines, newStart, newLines, nil
+}
+
+// parseRange parses "l[,s]"; the length s defaults to 1.
+func parseRange(text string) (start, length int, err error) {
+	length = 1
+	if i := strings.IndexByte(text, ','); i >= 0 {
+		if length, err = strconv.Atoi(text[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range %q", text)
+		}
+		text = text[:i]
+	}
+	if start, err = strconv.Atoi(text); err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range %q", text)
+	}
+	return start, length, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package unidiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const diff = `diff --git a/foo.go b/foo.go
+index 1111111..2222222 100644
+--- a/foo.go
++++ b/foo.go
+@@ -1,4 +1,5 @@
+ package foo
++// added
+ 
+-func A() {}
++func A() { println() }
+ func B() {}
+@@ -10,0 +12,2 @@ func C() {
++	x := 1
++	_ = x
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package foo
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package foo
++--- not a header
+diff --git a/removed_only.go b/removed_only.go
+--- a/removed_only.go
++++ b/removed_only.go
+@@ -3,2 +3 @@
+-x
+ y
+\ No newline at end of file
+`
+
+func TestParse(t *testing.T) {
+	files, err := Parse(strings.NewReader(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []*File{
+		{Name: "foo.go", Lines: []int{2, 4, 12, 13}},
+		{Name: "new.go", Lines: []int{1, 2}},
+	}, files)
+}
+
+func TestParseMalformedHunk(t *testing.T) {
+	_, err := Parse(strings.NewReader("+++ b/foo.go\n@@ -1,x +1 @@\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/axw/gocov"
+)
+
+// functionEntries returns the number of times fn was entered, which
+// is taken to be the count of its first statement.
+func functionEntries(fn *gocov.Function) int64 {
+	var first *gocov.Statement
+	for _, stmt := range fn.Statements {
+		if first == nil || stmt.Start < first.Start {
+			first = stmt
+		}
+	}
+	if first == nil {
+		return 0
+	}
+	return first.Reached
+}
+
+// printLcovReport writes the report as an LCOV tracefile, with one
+// record per source file.
+func printLcovReport(w io.Writer, r *report) error {
+	bw := bufio.NewWriter(w)
+	for _, pkg := range r.packages {
+		for _, file := range packageFiles(pkg) {
+			fmt.Fprintln(bw, "TN:")
+			fmt.Fprintf(bw, "SF:%s\n", file.name)
+			var functionsHit int
+			for _, fn := range file.functions {
+				fmt.Fprintf(bw, "FN:%d,%s\n", fn.StartLine, fn.Name)
+			}
+			for _, fn := range file.functions {
+				entries := functionEntries(fn)
+				if entries > 0 {
+					functionsHit++
+				}
+				fmt.Fprintf(bw, "FNDA:%d,%s\n", entries, fn.Name)
+			}
+			fmt.Fprintf(bw, "FNF:%d\n", len(file.functions))
+			fmt.Fprintf(bw, "FNH:%d\n", functionsHit)
+
+			hits, lines := lineHits(file.functions...)
+			var linesHit int
+			for _, line := range lines {
+				if hits[line] > 0 {
+					linesHit++
+				}
+				fmt.Fprintf(bw, "DA:%d,%d\n", line, hits[line])
+			}
+			fmt.Fprintf(bw, "LF:%d\n", len(lines))
+			fmt.Fprintf(bw, "LH:%d\n", linesHit)
+			fmt.Fprintln(bw, "end_of_record")
+		}
+	}
+	return bw.Flush()
+}
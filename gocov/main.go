@@ -25,7 +25,9 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/axw/gocov"
 	"github.com/axw/gocov/gocov/convert"
@@ -35,9 +37,15 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "Usage:\n\n\tgocov command [arguments]\n\n")
 	fmt.Fprintf(os.Stderr, "The commands are:\n\n")
 	fmt.Fprintf(os.Stderr, "\tannotate\n")
+	fmt.Fprintf(os.Stderr, "\tapi\n")
 	fmt.Fprintf(os.Stderr, "\tconvert\n")
+	fmt.Fprintf(os.Stderr, "\tdiff\n")
+	fmt.Fprintf(os.Stderr, "\tdiffcover\n")
+	fmt.Fprintf(os.Stderr, "\thtml\n")
+	fmt.Fprintf(os.Stderr, "\tmerge\n")
 	fmt.Fprintf(os.Stderr, "\treport\n")
 	fmt.Fprintf(os.Stderr, "\ttest\n")
+	fmt.Fprintf(os.Stderr, "\ttree\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	flag.PrintDefaults()
 	os.Exit(2)
@@ -56,6 +64,98 @@ func unmarshalJson(data []byte) (packages []*gocov.Package, err error) {
 	return
 }
 
+var (
+	convertFlags   = flag.NewFlagSet("convert", flag.ExitOnError)
+	convertDirFlag = convertFlags.String(
+		"dir", "",
+		"Convert the binary coverage data in the specified (comma-separated) GOCOVERDIR directories")
+	convertExcludeFlag = convertFlags.String(
+		"exclude", "",
+		"Skip files matching the specified (comma-separated) glob patterns, in which ** matches any number of directories")
+	convertSkipGeneratedFlag = convertFlags.Bool(
+		"skip-generated", false,
+		"Skip generated files, marked with a \"Code generated ... DO NOT EDIT.\" comment (default true with -dir)")
+)
+
+// newConvertConfig returns a convert.Config with the given
+// comma-separated exclude patterns.
+func newConvertConfig(exclude string, skipGenerated bool) *convert.Config {
+	config := &convert.Config{SkipGenerated: skipGenerated}
+	if exclude != "" {
+		config.Exclude = strings.Split(exclude, ",")
+	}
+	return config
+}
+
+// isFlagSet reports whether the named flag was set on the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// convertConfig returns the convert.Config for the convert flags.
+// Binary coverage data is converted with generated files skipped,
+// unless -skip-generated=false is given; cover profiles are converted
+// with all files unless -skip-generated is given, as they always have
+// been.
+func convertConfig() *convert.Config {
+	skipGenerated := *convertSkipGeneratedFlag
+	if *convertDirFlag != "" && !isFlagSet(convertFlags, "skip-generated") {
+		skipGenerated = true
+	}
+	return newConvertConfig(*convertExcludeFlag, skipGenerated)
+}
+
+func convertCoverage() (rc int) {
+	convertFlags.Parse(flag.Args()[1:])
+	config := convertConfig()
+	var out []byte
+	var err error
+	switch {
+	case *convertDirFlag != "" && convertFlags.NArg() > 0:
+		fmt.Fprintln(os.Stderr, "cannot convert both cover profiles and -dir")
+		return 1
+	case *convertDirFlag != "":
+		out, err = config.ConvertDirs(strings.Split(*convertDirFlag, ",")...)
+	case convertFlags.NArg() > 0:
+		out, err = config.ConvertProfiles(convertFlags.Args()...)
+	default:
+		fmt.Fprintln(os.Stderr, "missing cover profile")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
+
+// readCoverage reads the gocov JSON coverage data from the named file.
+// The special filename "-" may be used to indicate standard input.
+func readCoverage(filename string) ([]*gocov.Package, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage file: %s", err)
+	}
+	packages, err := unmarshalJson(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal coverage data: %s", err)
+	}
+	return packages, nil
+}
+
 func main() {
 	flag.Usage = usage
 	flag.Parse()
@@ -64,23 +164,26 @@ func main() {
 	if flag.NArg() > 0 {
 		command = flag.Arg(0)
 		switch command {
+		case "api":
+			os.Exit(apiReport())
 		case "convert":
-			if flag.NArg() <= 1 {
-				fmt.Fprintln(os.Stderr, "missing cover profile")
-				os.Exit(1)
-			}
-			out, err := convert.ConvertProfiles(flag.Args()[1:]...)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "error:", err)
-				os.Exit(1)
-			}
-			os.Stdout.Write(out)
+			os.Exit(convertCoverage())
+		case "diff":
+			os.Exit(diffReport())
+		case "diffcover":
+			os.Exit(diffCoverage())
+		case "html":
+			os.Exit(htmlReport())
+		case "merge":
+			os.Exit(mergeCoverage())
 		case "annotate":
 			os.Exit(annotateSource())
 		case "report":
 			os.Exit(reportCoverage())
+		case "tree":
+			os.Exit(treeReport())
 		case "test":
-			if err := runTests(flag.Args()[1:]); err != nil {
+			if err := runTests(flag.Args()[1:], os.Stdout, os.Stderr); err != nil {
 				fmt.Fprintln(os.Stderr, "error:", err)
 				os.Exit(1)
 			}
@@ -0,0 +1,56 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestConvertConfigSkipGenerated(t *testing.T) {
+	tests := []struct {
+		args []string
+		skip bool
+	}{
+		{[]string{"c.out"}, false},
+		{[]string{"-skip-generated", "c.out"}, true},
+		{[]string{"-dir", "covdata"}, true},
+		{[]string{"-dir", "covdata", "-skip-generated=false"}, false},
+	}
+	flags, dir, exclude, skipGenerated := convertFlags, convertDirFlag, convertExcludeFlag, convertSkipGeneratedFlag
+	defer func() {
+		convertFlags, convertDirFlag, convertExcludeFlag, convertSkipGeneratedFlag = flags, dir, exclude, skipGenerated
+	}()
+	for _, test := range tests {
+		// A new flag set, so that flags set by earlier cases are not
+		// seen as set.
+		convertFlags = flag.NewFlagSet("convert", flag.ContinueOnError)
+		convertDirFlag = convertFlags.String("dir", "", "")
+		convertExcludeFlag = convertFlags.String("exclude", "", "")
+		convertSkipGeneratedFlag = convertFlags.Bool("skip-generated", false, "")
+		if err := convertFlags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if skip := convertConfig().SkipGenerated; skip != test.skip {
+			t.Errorf("%q: SkipGenerated = %v, want %v", test.args, skip, test.skip)
+		}
+	}
+}
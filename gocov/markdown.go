@@ -0,0 +1,136 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+)
+
+// printMarkdownReport writes the report as Markdown, for posting on
+// pull requests: a table of the coverage of each package and the
+// total, followed by a collapsible section for each package listing
+// up to lowest of its least covered functions. If baseline is non-nil,
+// the table includes the change in coverage since the baseline.
+func printMarkdownReport(w io.Writer, r *report, lowest int, baseline *summary) error {
+	bw := bufio.NewWriter(w)
+	s := newSummary(r)
+	var basePackages map[string]*summaryPackage
+	if baseline != nil {
+		basePackages = make(map[string]*summaryPackage)
+		for _, pkg := range baseline.Packages {
+			basePackages[pkg.Name] = pkg
+		}
+	}
+
+	fmt.Fprintln(bw, "## Coverage")
+	fmt.Fprintln(bw)
+	if baseline != nil {
+		fmt.Fprintln(bw, "| Package | Coverage | Statements | Change |")
+		fmt.Fprintln(bw, "| --- | ---: | ---: | ---: |")
+	} else {
+		fmt.Fprintln(bw, "| Package | Coverage | Statements |")
+		fmt.Fprintln(bw, "| --- | ---: | ---: |")
+	}
+	for _, pkg := range s.Packages {
+		fmt.Fprintf(bw, "| `%s` | %.2f%% | %d/%d |",
+			pkg.Name, pkg.Percent, pkg.Reached, pkg.Total)
+		if baseline != nil {
+			if base := basePackages[pkg.Name]; base != nil {
+				fmt.Fprintf(bw, " %s |", markdownDelta(pkg.summaryCoverage, base.summaryCoverage))
+			} else {
+				fmt.Fprint(bw, " new |")
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintf(bw, "| **Total** | **%.2f%%** | **%d/%d** |",
+		s.Total.Percent, s.Total.Reached, s.Total.Total)
+	if baseline != nil {
+		fmt.Fprintf(bw, " **%s** |", markdownDelta(s.Total, baseline.Total))
+	}
+	fmt.Fprintln(bw)
+
+	if lowest > 0 {
+		for _, pkg := range r.packages {
+			printLowestFunctions(bw, pkg.Name, functionReports(pkg), lowest)
+		}
+	}
+	return bw.Flush()
+}
+
+// markdownDelta formats the change in coverage from base to c, in
+// percentage points. A change that rounds to zero is shown unsigned.
+func markdownDelta(c, base summaryCoverage) string {
+	delta := math.Round((c.Percent-base.Percent)*100) / 100
+	if delta == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%+.2f%%", delta)
+}
+
+// printLowestFunctions writes a collapsible section listing up to n of
+// the package's functions with the lowest coverage, ordered by
+// increasing coverage and then decreasing number of missed statements.
+// Functions that are fully covered or have no statements are omitted,
+// as is the section if there are none.
+func printLowestFunctions(w io.Writer, pkgName string, functions reportFunctionList, n int) {
+	var uncovered reportFunctionList
+	for _, fn := range functions {
+		if fn.missed() > 0 {
+			uncovered = append(uncovered, fn)
+		}
+	}
+	if len(uncovered) == 0 {
+		return
+	}
+	sort.SliceStable(uncovered, func(i, j int) bool {
+		a, b := uncovered[i], uncovered[j]
+		pa := percentage(a.statementsReached, len(a.Statements))
+		pb := percentage(b.statementsReached, len(b.Statements))
+		if pa != pb {
+			return pa < pb
+		}
+		return a.missed() > b.missed()
+	})
+	if len(uncovered) > n {
+		uncovered = uncovered[:n]
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "<details>")
+	fmt.Fprintf(w, "<summary>Least covered functions in <code>%s</code></summary>\n", pkgName)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Function | File | Coverage | Statements |")
+	fmt.Fprintln(w, "| --- | --- | ---: | ---: |")
+	for _, fn := range uncovered {
+		fmt.Fprintf(w, "| `%s` | `%s:%d` | %.2f%% | %d/%d |\n",
+			fn.Name, filepath.Base(fn.File), fn.StartLine,
+			percentage(fn.statementsReached, len(fn.Statements)),
+			fn.statementsReached, len(fn.Statements))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "</details>")
+}
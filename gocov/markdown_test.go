@@ -0,0 +1,114 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestPrintMarkdownReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printMarkdownReport(&buf, goldenReport(t), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "markdown.md", buf.Bytes())
+}
+
+func TestPrintMarkdownReportBaseline(t *testing.T) {
+	baseline := &summary{
+		Version: summaryVersion,
+		Total:   newSummaryCoverage(4, 8),
+		Packages: []*summaryPackage{
+			{Name: "example.com/a", summaryCoverage: newSummaryCoverage(1, 2)},
+			{Name: "example.com/removed", summaryCoverage: newSummaryCoverage(1, 1)},
+		},
+	}
+	var buf bytes.Buffer
+	if err := printMarkdownReport(&buf, goldenReport(t), 5, baseline); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "markdown_baseline.md", buf.Bytes())
+}
+
+func TestMarkdownDelta(t *testing.T) {
+	tests := []struct {
+		c, base float64
+		want    string
+	}{
+		{50, 50, "0.00%"},
+		{42.86, 50, "-7.14%"},
+		{100, 42.86, "+57.14%"},
+		{0.01, 0, "+0.01%"},
+		{33.33, 33.33, "0.00%"},
+		{75.334, 75.336, "0.00%"},
+	}
+	for _, test := range tests {
+		got := markdownDelta(summaryCoverage{Percent: test.c}, summaryCoverage{Percent: test.base})
+		if got != test.want {
+			t.Errorf("markdownDelta(%v, %v) = %q, want %q", test.c, test.base, got, test.want)
+		}
+	}
+}
+
+func TestPrintLowestFunctions(t *testing.T) {
+	r := testReport(t, &gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+		testFunction("Full", "/src/a/a.go", 1, 1, 1),
+		testFunction("HalfOfTwo", "/src/a/a.go", 10, 1, 0),
+		testFunction("HalfOfFour", "/src/a/a.go", 20, 1, 1, 0, 0),
+		testFunction("None", "/src/a/a.go", 30, 0),
+		testFunction("Empty", "/src/a/a.go", 40),
+	}})
+	functions := functionReports(r.packages[0])
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"None"}},
+		{2, []string{"None", "HalfOfFour"}},
+		{10, []string{"None", "HalfOfFour", "HalfOfTwo"}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		printLowestFunctions(&buf, "example.com/a", functions, test.n)
+		var names []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, "| `") {
+				names = append(names, strings.Split(line, "`")[1])
+			}
+		}
+		if strings.Join(names, ",") != strings.Join(test.want, ",") {
+			t.Errorf("lowest %d: got %v, want %v", test.n, names, test.want)
+		}
+	}
+
+	// Fully covered and empty functions are omitted, as is the section
+	// if nothing remains.
+	var buf bytes.Buffer
+	printLowestFunctions(&buf, "example.com/a", functions[:1], 5)
+	if buf.Len() != 0 {
+		t.Errorf("section written for a fully covered package:\n%s", buf.String())
+	}
+}
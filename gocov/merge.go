@@ -0,0 +1,85 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocovutil"
+)
+
+var (
+	mergeFlags    = flag.NewFlagSet("merge", flag.ExitOnError)
+	mergeModeFlag = mergeFlags.String(
+		"mode", "sum",
+		"How to combine statement counts: sum, max, or set (reached or not)")
+	mergeLenientFlag = mergeFlags.Bool(
+		"lenient", false,
+		"Report packages whose structure does not match as warnings, keeping the first occurrence, rather than failing")
+	mergeReconcileFlag = mergeFlags.Bool(
+		"reconcile", false,
+		"Tolerate source changes between runs, matching functions by file base name and name and statements by relative position; entries that cannot be matched are reported and dropped")
+)
+
+func mergeCoverage() (rc int) {
+	mergeFlags.Parse(flag.Args()[1:])
+	if mergeFlags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "missing coverage file\n")
+		return 1
+	}
+	mode, err := gocov.ParseMergeMode(*mergeModeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	var ps gocovutil.Packages
+	for _, filename := range mergeFlags.Args() {
+		packages, err := readCoverage(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			return 1
+		}
+		for _, pkg := range packages {
+			if *mergeReconcileFlag {
+				for _, m := range ps.ReconcilePackage(pkg, mode) {
+					fmt.Fprintf(os.Stderr, "warning: %s: %s\n", filename, m)
+				}
+				continue
+			}
+			if err := ps.MergePackage(pkg, mode); err != nil {
+				if !*mergeLenientFlag {
+					fmt.Fprintf(os.Stderr, "%s: cannot merge package %q: %s\n", filename, pkg.Name, err)
+					return 1
+				}
+				fmt.Fprintf(os.Stderr, "warning: %s: skipping package %q: %s\n", filename, pkg.Name, err)
+			}
+		}
+	}
+	if err := marshalJson(os.Stdout, ps); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
@@ -23,11 +23,14 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go/token"
 	"io"
 	"io/ioutil"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -41,16 +44,24 @@ type report struct {
 
 type reportFunction struct {
 	*gocov.Function
+	pkg               string
 	statementsReached int
 }
 
+// missed returns the number of statements that were not reached.
+func (f reportFunction) missed() int {
+	return len(f.Statements) - f.statementsReached
+}
+
 type reportFunctionList []reportFunction
 
 func (l reportFunctionList) Len() int {
 	return len(l)
 }
 
-// TODO make sort method configurable?
+// Less orders functions by increasing coverage, and then by increasing
+// number of statements. The report lists functions in the reverse of
+// this order by default; see sortFunctions for the alternatives.
 func (l reportFunctionList) Less(i, j int) bool {
 	var left, right float64
 	if len(l[i].Statements) > 0 {
@@ -69,14 +80,6 @@ func (l reportFunctionList) Swap(i, j int) {
 	l[i], l[j] = l[j], l[i]
 }
 
-type reverse struct {
-	sort.Interface
-}
-
-func (r reverse) Less(i, j int) bool {
-	return r.Interface.Less(j, i)
-}
-
 // NewReport creates a new report.
 func newReport() (r *report) {
 	r = &report{}
@@ -84,17 +87,20 @@ func newReport() (r *report) {
 }
 
 // AddPackage adds a package's coverage information to the report.
-func (r *report) addPackage(p *gocov.Package) {
+// If the report already holds a package with the same name, the
+// coverage is accumulated; an error is returned if the packages'
+// structures do not match.
+func (r *report) addPackage(p *gocov.Package) error {
 	i := sort.Search(len(r.packages), func(i int) bool {
 		return r.packages[i].Name >= p.Name
 	})
 	if i < len(r.packages) && r.packages[i].Name == p.Name {
-		r.packages[i].Accumulate(p)
-	} else {
-		head := r.packages[:i]
-		tail := append([]*gocov.Package{p}, r.packages[i:]...)
-		r.packages = append(head, tail...)
+		return r.packages[i].Accumulate(p)
 	}
+	head := r.packages[:i]
+	tail := append([]*gocov.Package{p}, r.packages[i:]...)
+	r.packages = append(head, tail...)
+	return nil
 }
 
 // Clear clears the coverage information from the report.
@@ -113,7 +119,7 @@ func functionReports(pkg *gocov.Package) reportFunctionList {
 				reached++
 			}
 		}
-		functions[i] = reportFunction{fn, reached}
+		functions[i] = reportFunction{fn, pkg.Name, reached}
 	}
 
 	return functions
@@ -127,8 +133,6 @@ func (r *report) printTotalCoverage(w io.Writer) {
 
 	for _, pkg := range r.packages {
 		functions := functionReports(pkg)
-		sort.Sort(reverse{functions})
-
 		for _, fn := range functions {
 			reached := fn.statementsReached
 			totalStatements += len(fn.Statements)
@@ -144,25 +148,123 @@ func (r *report) printTotalCoverage(w io.Writer) {
 	fmt.Fprintln(w)
 }
 
-// PrintReport prints a coverage report to the given writer.
+// PrintReport prints a coverage report to the given writer, with
+// functions grouped by package and sorted by decreasing coverage.
 func printReport(w io.Writer, r *report) {
+	printSortedReport(w, r, "coverage", "package")
+}
+
+// printSortedReport prints a coverage report to the given writer, with
+// functions sorted in the named order (see sortFunctions) within the
+// named groups (see reportGroups). Each group is followed by a line
+// summarizing its coverage, unless the functions are not grouped.
+func printSortedReport(w io.Writer, r *report, order, group string) {
 	w = tabwriter.NewWriter(w, 0, 8, 0, '\t', 0)
 	//fmt.Fprintln(w, "Package\tFunction\tStatements\t")
 	//fmt.Fprintln(w, "-------\t--------\t---------\t")
-	for _, pkg := range r.packages {
-		printPackage(w, pkg)
+	for _, g := range reportGroups(r, group) {
+		sortFunctions(g.functions, order)
+		printGroup(w, g, group != "none")
 		fmt.Fprintln(w)
 	}
 	r.printTotalCoverage(w)
 }
 
-func printPackage(w io.Writer, pkg *gocov.Package) {
-	functions := functionReports(pkg)
-	sort.Sort(reverse{functions})
+// reportGroup holds the functions of a report that are listed together.
+type reportGroup struct {
+	name      string
+	functions reportFunctionList
+}
+
+// reportGroups returns the functions of the report grouped by package,
+// file or the directory containing the package, in order of the group
+// name, or with group "none", in a single group.
+func reportGroups(r *report, group string) []*reportGroup {
+	byName := make(map[string]*reportGroup)
+	var groups []*reportGroup
+	for _, pkg := range r.packages {
+		for _, fn := range functionReports(pkg) {
+			var name string
+			switch group {
+			case "file":
+				name = pkg.Name + "/" + filepath.Base(fn.File)
+			case "directory":
+				name = path.Dir(pkg.Name)
+			case "none":
+			default:
+				name = pkg.Name
+			}
+			g := byName[name]
+			if g == nil {
+				g = &reportGroup{name: name}
+				byName[name] = g
+				groups = append(groups, g)
+			}
+			g.functions = append(g.functions, fn)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// sortFunctions sorts functions in the named order: "coverage", the
+// default, by decreasing coverage and then number of statements;
+// "name" by name; "file" by file and position; "statements" by
+// decreasing number of statements; and "missed" by decreasing number
+// of statements that were not reached. Functions that are equal in
+// that order are sorted by package, file and position, so that the
+// result does not depend on the order of the input.
+func sortFunctions(functions reportFunctionList, order string) {
+	var less func(a, b reportFunction) bool
+	switch order {
+	case "name":
+		less = func(a, b reportFunction) bool {
+			return a.Name < b.Name
+		}
+	case "file":
+		less = func(a, b reportFunction) bool {
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.Start < b.Start
+		}
+	case "statements":
+		less = func(a, b reportFunction) bool {
+			return len(a.Statements) > len(b.Statements)
+		}
+	case "missed":
+		less = func(a, b reportFunction) bool {
+			return a.missed() > b.missed()
+		}
+	default:
+		less = func(a, b reportFunction) bool {
+			return reportFunctionList{b, a}.Less(0, 1)
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		a, b := functions[i], functions[j]
+		switch {
+		case less(a, b):
+			return true
+		case less(b, a):
+			return false
+		case a.pkg != b.pkg:
+			return a.pkg < b.pkg
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Start != b.Start:
+			return a.Start < b.Start
+		}
+		return a.Name < b.Name
+	})
+}
 
+func printGroup(w io.Writer, g *reportGroup, summary bool) {
 	var longestFunctionName int
 	var totalStatements, totalReached int
-	for _, fn := range functions {
+	for _, fn := range g.functions {
 		reached := fn.statementsReached
 		totalStatements += len(fn.Statements)
 		totalReached += reached
@@ -174,9 +276,12 @@ func printPackage(w io.Writer, pkg *gocov.Package) {
 			longestFunctionName = len(fn.Name)
 		}
 		fmt.Fprintf(w, "%s/%s\t %s\t %.2f%% (%d/%d)\n",
-			pkg.Name, filepath.Base(fn.File), fn.Name, stmtPercent,
+			fn.pkg, filepath.Base(fn.File), fn.Name, stmtPercent,
 			reached, len(fn.Statements))
 	}
+	if !summary {
+		return
+	}
 
 	var funcPercent float64
 	if totalStatements > 0 {
@@ -184,14 +289,184 @@ func printPackage(w io.Writer, pkg *gocov.Package) {
 	}
 	summaryLine := strings.Repeat("-", longestFunctionName)
 	fmt.Fprintf(w, "%s\t %s\t %.2f%% (%d/%d)\n",
-		pkg.Name, summaryLine, funcPercent,
+		g.name, summaryLine, funcPercent,
 		totalReached, totalStatements)
 }
 
-func reportCoverage() (rc int) {
+var (
+	reportFlags      = flag.NewFlagSet("report", flag.ExitOnError)
+	reportFormatFlag = reportFlags.String(
+		"format", "text",
+		"Output format: text, cobertura, lcov, json or markdown")
+	reportSortFlag = reportFlags.String(
+		"sort", "coverage",
+		"Order of functions in the text report: name, file, coverage, statements or missed")
+	reportGroupFlag = reportFlags.String(
+		"group", "package",
+		"Grouping of functions in the text report: package, file, directory or none")
+	reportMinTotalFlag = reportFlags.Float64(
+		"min-total", 0,
+		"Fail if total statement coverage is below the specified percentage")
+	reportMinPackageFlag = reportFlags.Float64(
+		"min-package", 0,
+		"Fail if any package's statement coverage is below the specified percentage")
+	reportMinFunctionFlag = reportFlags.Float64(
+		"min-function", 0,
+		"Fail if any function's statement coverage is below the specified percentage")
+	reportUncalledFlag = reportFlags.Bool(
+		"uncalled", false,
+		"List the functions that were never called instead of statement coverage")
+	reportExportedFlag = reportFlags.Bool(
+		"exported", false,
+		"With -uncalled, list only exported functions and exported methods of exported types")
+	reportInterfacesFlag = reportFlags.Bool(
+		"interfaces", false,
+		"With -uncalled, list only methods that implement an interface method")
+	reportTestsFlag = reportFlags.Bool(
+		"tests", false,
+		"List the tests that reached each function, as recorded by \"gocov test -per-test\", instead of statement coverage")
+	reportFuncFlag = reportFlags.String(
+		"func", "",
+		"With -tests, list only the functions whose names match the specified regular expression")
+	reportLowestFlag = reportFlags.Int(
+		"lowest", 0,
+		"With -format=markdown, list up to the specified number of least covered functions in each package")
+	reportBaselineFlag = reportFlags.String(
+		"baseline", "",
+		"With -format=markdown, show the change in coverage since the specified JSON summary or coverage file")
+)
+
+// reportFile holds the functions of a package that are defined in
+// the same file.
+type reportFile struct {
+	name      string
+	functions []*gocov.Function
+}
+
+// packageFiles returns the functions of pkg grouped by file, ordered
+// by file name and then source position.
+func packageFiles(pkg *gocov.Package) []*reportFile {
+	byName := make(map[string]*reportFile)
+	var files []*reportFile
+	for _, fn := range pkg.Functions {
+		file := byName[fn.File]
+		if file == nil {
+			file = &reportFile{name: fn.File}
+			byName[fn.File] = file
+			files = append(files, file)
+		}
+		file.functions = append(file.functions, fn)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+	for _, file := range files {
+		sort.SliceStable(file.functions, func(i, j int) bool {
+			return file.functions[i].Start < file.functions[j].Start
+		})
+	}
+	return files
+}
+
+// statementCoverage returns the number of statements reached, and the
+// total number of statements, in the given functions.
+func statementCoverage(functions ...*gocov.Function) (reached, total int) {
+	for _, fn := range functions {
+		for _, stmt := range fn.Statements {
+			if stmt.Reached > 0 {
+				reached++
+			}
+		}
+		total += len(fn.Statements)
+	}
+	return reached, total
+}
+
+// lineHits returns the number of times each line containing the
+// start of a statement was reached, and the line numbers in order.
+// Where several statements start on the same line, the largest
+// count is used.
+func lineHits(functions ...*gocov.Function) (hits map[int]int64, lines []int) {
+	hits = make(map[int]int64)
+	for _, fn := range functions {
+		for _, stmt := range fn.Statements {
+			reached, ok := hits[stmt.StartLine]
+			if !ok {
+				lines = append(lines, stmt.StartLine)
+			}
+			if !ok || stmt.Reached > reached {
+				hits[stmt.StartLine] = stmt.Reached
+			}
+		}
+	}
+	sort.Ints(lines)
+	return hits, lines
+}
+
+// addLines sets the line and column of each function and statement
+// that lacks them, as in coverage data written by older versions of
+// gocov or by other tools, from its offsets in the source file. An
+// error is returned if a source file is needed but cannot be read.
+func (r *report) addLines() error {
+	fset := token.NewFileSet()
+	files := make(map[string]*token.File)
+	position := func(filename string, offset int) (token.Position, error) {
+		file := files[filename]
+		if file == nil {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return token.Position{}, fmt.Errorf("coverage data has no line numbers, and the source is unavailable: %s", err)
+			}
+			file = fset.AddFile(filename, -1, len(data))
+			file.SetLinesForContent(data)
+			files[filename] = file
+		}
+		if offset < 0 || offset > file.Size() {
+			return token.Position{}, fmt.Errorf("%s: offset %d is out of range; has the source changed?", filename, offset)
+		}
+		return file.Position(file.Pos(offset)), nil
+	}
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			if fn.StartLine == 0 {
+				start, err := position(fn.File, fn.Start)
+				if err != nil {
+					return err
+				}
+				end, err := position(fn.File, fn.End)
+				if err != nil {
+					return err
+				}
+				fn.StartLine, fn.StartCol = start.Line, start.Column
+				fn.EndLine, fn.EndCol = end.Line, end.Column
+			}
+			for _, stmt := range fn.Statements {
+				if stmt.StartLine != 0 {
+					continue
+				}
+				start, err := position(fn.File, stmt.Start)
+				if err != nil {
+					return err
+				}
+				end, err := position(fn.File, stmt.End)
+				if err != nil {
+					return err
+				}
+				stmt.StartLine, stmt.StartCol = start.Line, start.Column
+				stmt.EndLine, stmt.EndCol = end.Line, end.Column
+			}
+		}
+	}
+	return nil
+}
+
+// readReport reads the coverage data in the named files into a new
+// report. If no files are named, standard input is read instead. Files
+// that cannot be opened are skipped with a warning.
+func readReport(filenames []string) (*report, error) {
 	files := make([]*os.File, 0, 1)
-	if flag.NArg() > 1 {
-		for _, name := range flag.Args()[1:] {
+	if len(filenames) > 0 {
+		for _, name := range filenames {
 			file, err := os.Open(name)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to open file (%s): %s\n", name, err)
@@ -204,25 +479,140 @@ func reportCoverage() (rc int) {
 	}
 	report := newReport()
 	for _, file := range files {
+		name := file.Name()
 		data, err := ioutil.ReadAll(file)
+		if file != os.Stdin {
+			file.Close()
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to read coverage file: %s\n", err)
-			return 1
+			return nil, fmt.Errorf("failed to read coverage file: %s", err)
 		}
 		packages, err := unmarshalJson(data)
 		if err != nil {
-			fmt.Fprintf(
-				os.Stderr, "failed to unmarshal coverage data: %s\n", err)
-			return 1
+			return nil, fmt.Errorf("failed to unmarshal coverage data: %s", err)
 		}
 		for _, pkg := range packages {
-			report.addPackage(pkg)
+			if err := report.addPackage(pkg); err != nil {
+				return nil, fmt.Errorf("%s: cannot merge package %q: %s", name, pkg.Name, err)
+			}
 		}
-		if file != os.Stdin {
-			file.Close()
+	}
+	return report, nil
+}
+
+func reportCoverage() (rc int) {
+	reportFlags.Parse(flag.Args()[1:])
+	switch *reportFormatFlag {
+	case "text", "cobertura", "lcov", "json", "markdown":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown report format %q\n", *reportFormatFlag)
+		return 1
+	}
+
+	switch *reportSortFlag {
+	case "name", "file", "coverage", "statements", "missed":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown sort order %q\n", *reportSortFlag)
+		return 1
+	}
+	switch *reportGroupFlag {
+	case "package", "file", "directory", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown grouping %q\n", *reportGroupFlag)
+		return 1
+	}
+	if *reportUncalledFlag && *reportFormatFlag != "text" {
+		fmt.Fprintln(os.Stderr, "-uncalled requires the text format")
+		return 1
+	}
+	if *reportTestsFlag && (*reportFormatFlag != "text" || *reportUncalledFlag) {
+		fmt.Fprintln(os.Stderr, "-tests requires the text format, and cannot be used with -uncalled")
+		return 1
+	}
+	var funcPattern *regexp.Regexp
+	if *reportFuncFlag != "" {
+		if !*reportTestsFlag {
+			fmt.Fprintln(os.Stderr, "-func requires -tests")
+			return 1
+		}
+		var err error
+		if funcPattern, err = regexp.Compile(*reportFuncFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -func pattern: %s\n", err)
+			return 1
+		}
+	}
+	if (*reportLowestFlag > 0 || *reportBaselineFlag != "") && *reportFormatFlag != "markdown" {
+		fmt.Fprintln(os.Stderr, "-lowest and -baseline require the markdown format")
+		return 1
+	}
+
+	report, err := readReport(reportFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *reportFormatFlag != "text" || *reportUncalledFlag || *reportTestsFlag {
+		if err := report.addLines(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	switch *reportFormatFlag {
+	case "cobertura":
+		if err := printCoberturaReport(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+			return 1
+		}
+	case "lcov":
+		if err := printLcovReport(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+			return 1
+		}
+	case "json":
+		if err := printJSONReport(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+			return 1
+		}
+	case "markdown":
+		var baseline *summary
+		if *reportBaselineFlag != "" {
+			baseline, err = readSummary(*reportBaselineFlag)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+		if err := printMarkdownReport(os.Stdout, report, *reportLowestFlag, baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+			return 1
+		}
+	default:
+		if *reportUncalledFlag {
+			if err := reportUncalled(os.Stdout, report); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			break
+		}
+		if *reportTestsFlag {
+			printFunctionTests(os.Stdout, report, funcPattern)
+			break
+		}
+		fmt.Println()
+		printSortedReport(os.Stdout, report, *reportSortFlag, *reportGroupFlag)
+	}
+
+	violations := checkThresholds(report, thresholds{
+		total:    *reportMinTotalFlag,
+		pkg:      *reportMinPackageFlag,
+		function: *reportMinFunctionFlag,
+	})
+	if len(violations) > 0 {
+		fmt.Fprintln(os.Stderr, "coverage below threshold:")
+		for _, v := range violations {
+			fmt.Fprintf(os.Stderr, "\t%s\n", v)
 		}
+		return 1
 	}
-	fmt.Println()
-	printReport(os.Stdout, report)
 	return 0
 }
@@ -0,0 +1,214 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// goldenReport returns a report with several packages and files, and
+// functions that were fully, partly and not covered.
+func goldenReport(t *testing.T) *report {
+	return testReport(t,
+		&gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+			testFunction("Full", "/src/a/a.go", 1, 1, 2),
+			testFunction("Half", "/src/a/a.go", 10, 3, 0),
+			testFunction("T.Never", "/src/a/b.go", 5, 0, 0, 0),
+		}},
+		&gocov.Package{Name: "example.com/a/sub", Functions: []*gocov.Function{
+			testFunction("init", "/src/a/sub/sub.go", 3, 1),
+			testFunction("Empty", "/src/a/sub/sub.go", 8),
+		}},
+	)
+}
+
+// checkGolden compares output with the named file in testdata, or
+// with -update, writes it there.
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	filename := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(filename, output, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("output does not match %s; got:\n%s", filename, output)
+	}
+}
+
+func TestAddLines(t *testing.T) {
+	source := "package a\n\nfunc F() {\n\tprintln()\n}\n"
+	filename := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Coverage data without line numbers, as written by older
+	// versions of gocov.
+	fn := &gocov.Function{Name: "F", File: filename, Start: 11, End: 34}
+	stmt := &gocov.Statement{Start: 23, End: 32, Reached: 1}
+	fn.Statements = []*gocov.Statement{stmt}
+	r := testReport(t, &gocov.Package{Name: "a", Functions: []*gocov.Function{fn}})
+	if err := r.addLines(); err != nil {
+		t.Fatal(err)
+	}
+	if fn.StartLine != 3 || fn.StartCol != 1 || fn.EndLine != 5 || fn.EndCol != 2 {
+		t.Errorf("unexpected function position %d.%d,%d.%d", fn.StartLine, fn.StartCol, fn.EndLine, fn.EndCol)
+	}
+	if stmt.StartLine != 4 || stmt.StartCol != 2 || stmt.EndLine != 4 || stmt.EndCol != 11 {
+		t.Errorf("unexpected statement position %d.%d,%d.%d", stmt.StartLine, stmt.StartCol, stmt.EndLine, stmt.EndCol)
+	}
+
+	fn.File = filepath.Join(t.TempDir(), "missing.go")
+	fn.StartLine, stmt.StartLine = 0, 0
+	if err := r.addLines(); err == nil {
+		t.Error("expected an error for a missing source file")
+	}
+}
+
+// sortReport returns a report whose functions tie in each sort order.
+func sortReport(t *testing.T) *report {
+	return testReport(t,
+		&gocov.Package{Name: "example.com/x", Functions: []*gocov.Function{
+			testFunction("A", "/src/x/x.go", 1, 1, 0),
+			testFunction("B", "/src/x/x.go", 10, 1, 1),
+			testFunction("A", "/src/x/y.go", 1, 0, 0),
+			testFunction("C", "/src/x/y.go", 10, 1, 0),
+		}},
+		&gocov.Package{Name: "example.com/w", Functions: []*gocov.Function{
+			testFunction("D", "/src/w/w.go", 1, 1, 0, 0, 0),
+		}},
+		&gocov.Package{Name: "example.com/w/v", Functions: []*gocov.Function{
+			testFunction("E", "/src/w/v/v.go", 1, 1),
+		}},
+	)
+}
+
+// functionNames returns the file base name and name of each function.
+func functionNames(functions reportFunctionList) []string {
+	names := make([]string, len(functions))
+	for i, fn := range functions {
+		names[i] = filepath.Base(fn.File) + ":" + fn.Name
+	}
+	return names
+}
+
+func TestSortFunctions(t *testing.T) {
+	var functions reportFunctionList
+	for _, g := range reportGroups(sortReport(t), "none") {
+		functions = append(functions, g.functions...)
+	}
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{"coverage", []string{"x.go:B", "v.go:E", "x.go:A", "y.go:C", "w.go:D", "y.go:A"}},
+		{"name", []string{"x.go:A", "y.go:A", "x.go:B", "y.go:C", "w.go:D", "v.go:E"}},
+		{"file", []string{"v.go:E", "w.go:D", "x.go:A", "x.go:B", "y.go:A", "y.go:C"}},
+		{"statements", []string{"w.go:D", "x.go:A", "x.go:B", "y.go:A", "y.go:C", "v.go:E"}},
+		{"missed", []string{"w.go:D", "y.go:A", "x.go:A", "y.go:C", "v.go:E", "x.go:B"}},
+	}
+	for _, test := range tests {
+		// The result must not depend on the order of the input.
+		for shift := 0; shift < len(functions); shift++ {
+			input := append(append(reportFunctionList{}, functions[shift:]...), functions[:shift]...)
+			for _, in := range []reportFunctionList{input, reversed(input)} {
+				sortFunctions(in, test.order)
+				if got := functionNames(in); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("sort %s: got %v, want %v", test.order, got, test.want)
+				}
+			}
+		}
+	}
+}
+
+func reversed(functions reportFunctionList) reportFunctionList {
+	r := make(reportFunctionList, len(functions))
+	for i, fn := range functions {
+		r[len(r)-1-i] = fn
+	}
+	return r
+}
+
+func TestReportGroups(t *testing.T) {
+	tests := []struct {
+		group string
+		want  map[string][]string
+		names []string
+	}{{
+		group: "package",
+		names: []string{"example.com/w", "example.com/w/v", "example.com/x"},
+		want: map[string][]string{
+			"example.com/w":   {"w.go:D"},
+			"example.com/w/v": {"v.go:E"},
+			"example.com/x":   {"x.go:A", "x.go:B", "y.go:A", "y.go:C"},
+		},
+	}, {
+		group: "file",
+		names: []string{"example.com/w/v/v.go", "example.com/w/w.go", "example.com/x/x.go", "example.com/x/y.go"},
+		want: map[string][]string{
+			"example.com/w/v/v.go": {"v.go:E"},
+			"example.com/w/w.go":   {"w.go:D"},
+			"example.com/x/x.go":   {"x.go:A", "x.go:B"},
+			"example.com/x/y.go":   {"y.go:A", "y.go:C"},
+		},
+	}, {
+		group: "directory",
+		names: []string{"example.com", "example.com/w"},
+		want: map[string][]string{
+			"example.com":   {"w.go:D", "x.go:A", "x.go:B", "y.go:A", "y.go:C"},
+			"example.com/w": {"v.go:E"},
+		},
+	}, {
+		group: "none",
+		names: []string{""},
+		want: map[string][]string{
+			"": {"v.go:E", "w.go:D", "x.go:A", "x.go:B", "y.go:A", "y.go:C"},
+		},
+	}}
+	for _, test := range tests {
+		var names []string
+		for _, g := range reportGroups(sortReport(t), test.group) {
+			names = append(names, g.name)
+			sortFunctions(g.functions, "file")
+			if got := functionNames(g.functions); !reflect.DeepEqual(got, test.want[g.name]) {
+				t.Errorf("group %s: %q has %v, want %v", test.group, g.name, got, test.want[g.name])
+			}
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("group %s: got groups %q, want %q", test.group, names, test.names)
+		}
+	}
+}
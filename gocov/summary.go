@@ -0,0 +1,154 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+)
+
+// summaryVersion is the version of the JSON summary document. It must
+// be incremented whenever the document changes incompatibly.
+const summaryVersion = 1
+
+// summary is the JSON summary document written by
+// "gocov report -format=json".
+type summary struct {
+	Version  int
+	Total    summaryCoverage
+	Packages []*summaryPackage
+}
+
+// summaryCoverage holds the statement coverage of part of a report.
+// Percent is rounded to two decimal places, as in the text report.
+type summaryCoverage struct {
+	Reached int
+	Total   int
+	Percent float64
+}
+
+func newSummaryCoverage(reached, total int) summaryCoverage {
+	return summaryCoverage{
+		Reached: reached,
+		Total:   total,
+		Percent: math.Round(percentage(reached, total)*100) / 100,
+	}
+}
+
+type summaryPackage struct {
+	Name string
+	summaryCoverage
+	Files []*summaryFile
+}
+
+type summaryFile struct {
+	// Name is the base name of the file.
+	Name string
+	// Path is the file's path, as recorded in the coverage data.
+	Path string
+	summaryCoverage
+	Functions []*summaryFunction
+}
+
+type summaryFunction struct {
+	Name string
+	Line int
+	summaryCoverage
+}
+
+// newSummary returns the summary of the report, with packages, files
+// and functions ordered as in packageFiles.
+func newSummary(r *report) *summary {
+	s := &summary{Version: summaryVersion, Packages: []*summaryPackage{}}
+	var totalReached, totalStatements int
+	for _, pkg := range r.packages {
+		reached, total := statementCoverage(pkg.Functions...)
+		totalReached += reached
+		totalStatements += total
+		sp := &summaryPackage{
+			Name:            pkg.Name,
+			summaryCoverage: newSummaryCoverage(reached, total),
+			Files:           []*summaryFile{},
+		}
+		for _, file := range packageFiles(pkg) {
+			reached, total := statementCoverage(file.functions...)
+			sf := &summaryFile{
+				Name:            filepath.Base(file.name),
+				Path:            file.name,
+				summaryCoverage: newSummaryCoverage(reached, total),
+				Functions:       []*summaryFunction{},
+			}
+			for _, fn := range file.functions {
+				reached, total := statementCoverage(fn)
+				sf.Functions = append(sf.Functions, &summaryFunction{
+					Name:            fn.Name,
+					Line:            fn.StartLine,
+					summaryCoverage: newSummaryCoverage(reached, total),
+				})
+			}
+			sp.Files = append(sp.Files, sf)
+		}
+		s.Packages = append(s.Packages, sp)
+	}
+	s.Total = newSummaryCoverage(totalReached, totalStatements)
+	return s
+}
+
+// printJSONReport writes the summary of the report as indented JSON.
+func printJSONReport(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newSummary(r))
+}
+
+// readSummary reads a JSON summary document from the named file. A
+// gocov coverage document may be given instead, and is summarized.
+func readSummary(filename string) (*summary, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary: %s", err)
+	}
+	var s summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal summary: %s", err)
+	}
+	switch {
+	case s.Version > summaryVersion:
+		return nil, fmt.Errorf("%s: unsupported summary version %d", filename, s.Version)
+	case s.Version > 0:
+		return &s, nil
+	}
+	packages, err := unmarshalJson(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal coverage data: %s", err)
+	}
+	r := newReport()
+	for _, pkg := range packages {
+		if err := r.addPackage(pkg); err != nil {
+			return nil, fmt.Errorf("%s: cannot merge package %q: %s", filename, pkg.Name, err)
+		}
+	}
+	return newSummary(r), nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printJSONReport(&buf, goldenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "summary.json", buf.Bytes())
+}
+
+func TestReadSummary(t *testing.T) {
+	r := goldenReport(t)
+	expected := newSummary(r)
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	if err := printJSONReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	summaryFile := filepath.Join(dir, "summary.json")
+	if err := os.WriteFile(summaryFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSummary(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("summary did not round trip: got %+v, want %+v", s, expected)
+	}
+
+	// A coverage document is summarized as it is read.
+	buf.Reset()
+	if err := marshalJson(&buf, r.packages); err != nil {
+		t.Fatal(err)
+	}
+	coverageFile := filepath.Join(dir, "coverage.json")
+	if err := os.WriteFile(coverageFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err = readSummary(coverageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("coverage document summarized as %+v, want %+v", s, expected)
+	}
+}
+
+func TestReadSummaryVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "summary.json")
+	if err := os.WriteFile(filename, []byte(`{"Version": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := readSummary(filename)
+	if err == nil || !strings.Contains(err.Error(), "unsupported summary version 2") {
+		t.Errorf("readSummary = %v, want unsupported version error", err)
+	}
+}
@@ -22,15 +22,19 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
-	"github.com/axw/gocov/gocov/convert"
+	"github.com/axw/gocov/gocov/internal/instrument"
 	"github.com/axw/gocov/gocov/internal/testflag"
 )
 
@@ -57,9 +61,256 @@ func resolvePackages(pkgs []string) ([]string, error) {
 	return resolvedPkgs, nil
 }
 
-func runTests(args []string) error {
+var (
+	testFlags       = flag.NewFlagSet("test", flag.ExitOnError)
+	testPerTestFlag = testFlags.Bool(
+		"per-test", false,
+		"Run each top-level test separately, recording which tests reached each statement")
+	testJobsFlag = testFlags.Int(
+		"jobs", 1,
+		"Number of go test invocations to run in parallel; their output is buffered and written as each completes")
+	testKeepGoingFlag = testFlags.Bool(
+		"keep-going", false,
+		"Continue after test failures, converting the coverage of all packages and exiting non-zero at the end")
+	testBranchesFlag = testFlags.Bool(
+		"branches", false,
+		"Also record branch coverage, running the tests again with instrumented sources")
+	testExcludeFlag = testFlags.String(
+		"exclude", "",
+		"Skip files matching the specified (comma-separated) glob patterns, in which ** matches any number of directories")
+	testSkipGeneratedFlag = testFlags.Bool(
+		"skip-generated", false,
+		"Skip generated files, marked with a \"Code generated ... DO NOT EDIT.\" comment")
+)
+
+// parseTestFlags extracts the flags defined in testFlags from args,
+// returning the remaining arguments to pass on to "go test". Flags
+// may appear anywhere before -args.
+func parseTestFlags(args []string) (rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			return append(rest, args[i:]...), nil
+		}
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		value, hasValue := "", false
+		if equals := strings.Index(name, "="); equals >= 0 {
+			name, value, hasValue = name[:equals], name[equals+1:], true
+		}
+		f := testFlags.Lookup(name)
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+		}
+		if err := testFlags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for flag -%s: %v", value, name, err)
+		}
+	}
+	return rest, nil
+}
+
+// testRun describes a single invocation of "go test".
+type testRun struct {
+	pkg string
+
+	// test, if non-empty, is the name of the only top-level test
+	// to run.
+	test string
+
+	coverFile string
+}
+
+// name returns the package, or package and test, that r runs.
+func (r *testRun) name() string {
+	if r.test != "" {
+		return r.pkg + "." + r.test
+	}
+	return r.pkg
+}
+
+func (r *testRun) args(testFlags []string) []string {
+	testFlags, testArgs := splitArgs(testFlags)
+	args := append([]string{"test", "-coverprofile", r.coverFile}, testFlags...)
+	if r.test != "" {
+		// This comes after the user's flags so that it takes
+		// precedence over any -run flag of theirs.
+		args = append(args, "-run", "^"+r.test+"$")
+	}
+	args = append(args, r.pkg)
+	return append(args, testArgs...)
+}
+
+// splitArgs splits flags at -args, returning the flags for "go test"
+// and, starting with -args, the arguments for the test binary.
+func splitArgs(flags []string) (testFlags, testArgs []string) {
+	for i, flag := range flags {
+		if flag == "-args" || flag == "--args" {
+			return flags[:i:i], flags[i:]
+		}
+	}
+	return flags, nil
+}
+
+// listTests returns the names of the top-level tests, examples and fuzz
+// targets in pkg, as reported by "go test -list".
+func listTests(pkg string, testFlags []string, stderr io.Writer) ([]string, error) {
+	var buf bytes.Buffer
+	testFlags, testArgs := splitArgs(testFlags)
+	cmdArgs := append([]string{"test"}, testFlags...)
+	cmdArgs = append(cmdArgs, "-list", ".", pkg)
+	cmdArgs = append(cmdArgs, testArgs...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Stdout = &buf
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	var tests []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.ContainsAny(line, " \t") {
+			// "ok", "?" and other summary lines.
+			continue
+		}
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if strings.HasPrefix(line, prefix) {
+				tests = append(tests, line)
+				break
+			}
+		}
+	}
+	return tests, nil
+}
+
+// runParallel calls f for each i in [0, n), with at most p calls running
+// at once. Once a call fails no further calls are started, and the first
+// error is returned.
+func runParallel(p, n int, f func(i int) error) error {
+	var (
+		mu       sync.Mutex
+		next     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < p; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if firstErr != nil || next >= n {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				if err := f(i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// testFailures records the packages and tests that failed when
+// running with -keep-going.
+type testFailures struct {
+	mu       sync.Mutex
+	names    []string
+	packages map[string]bool
+}
+
+// check returns err, unless -keep-going is set, in which case a non-nil
+// err is recorded as a failure of the named test run and nil is returned.
+func (f *testFailures) check(pkg, name string, err error) error {
+	if err == nil || !*testKeepGoingFlag {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.packages == nil {
+		f.packages = make(map[string]bool)
+	}
+	f.names = append(f.names, name)
+	f.packages[pkg] = true
+	return nil
+}
+
+// summarize writes the recorded failures to w, and returns an error
+// if there were any.
+func (f *testFailures) summarize(w io.Writer, npkgs int) error {
+	if len(f.names) == 0 {
+		return nil
+	}
+	sort.Strings(f.names)
+	for _, name := range f.names {
+		fmt.Fprintf(w, "FAIL\t%s\n", name)
+	}
+	return fmt.Errorf("tests failed in %d of %d packages", len(f.packages), npkgs)
+}
+
+// testOutput demultiplexes the output of concurrent "go test"
+// invocations. When buffering, each invocation's output is collected
+// and written to w in one piece once it completes; otherwise it is
+// written to w directly.
+type testOutput struct {
+	w        io.Writer
+	buffered bool
+	mu       sync.Mutex
+}
+
+func newTestOutput(w io.Writer, buffered bool) *testOutput {
+	return &testOutput{w: w, buffered: buffered}
+}
+
+// writer returns a writer for the output of one invocation, which must
+// be passed to flush when the invocation completes.
+func (o *testOutput) writer() io.Writer {
+	if o.buffered {
+		return &bytes.Buffer{}
+	}
+	return o.w
+}
+
+func (o *testOutput) flush(w io.Writer) {
+	if buf, ok := w.(*bytes.Buffer); ok && o.buffered {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.w.Write(buf.Bytes())
+	}
+}
+
+// runTests runs "go test" with the given arguments, writing the
+// converted coverage to stdout and the output of the tests to stderr.
+func runTests(args []string, stdout, stderr io.Writer) error {
+	args, err := parseTestFlags(args)
+	if err != nil {
+		return err
+	}
 	pkgs, testFlags := testflag.Split(args)
-	pkgs, err := resolvePackages(pkgs)
+	pkgs, err = resolvePackages(pkgs)
 	if err != nil {
 		return err
 	}
@@ -75,33 +326,164 @@ func runTests(args []string) error {
 		}
 	}()
 
+	parallelism := *testJobsFlag
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	output := newTestOutput(stderr, parallelism > 1)
+	var failures testFailures
+
+	pkgTests := make([][]string, len(pkgs))
+	if *testPerTestFlag {
+		err := runParallel(parallelism, len(pkgs), func(i int) error {
+			stderr := output.writer()
+			defer output.flush(stderr)
+			tests, err := listTests(pkgs[i], testFlags, stderr)
+			pkgTests[i] = tests
+			return failures.check(pkgs[i], pkgs[i], err)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Unique -coverprofile file names are used so that all the files can be
 	// later merged into a single file.
+	var runs []*testRun
 	for i, pkg := range pkgs {
-		coverFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", i))
-		cmdArgs := append([]string{"test", "-coverprofile", coverFile}, testFlags...)
-		cmdArgs = append(cmdArgs, pkg)
-		cmd := exec.Command("go", cmdArgs...)
+		tests := pkgTests[i]
+		if len(tests) == 0 {
+			// Without -per-test, and for packages without tests,
+			// the package is run once. This way packages without
+			// tests are reported, with no coverage.
+			tests = []string{""}
+		}
+		for _, test := range tests {
+			coverFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", len(runs)))
+			runs = append(runs, &testRun{pkg: pkg, test: test, coverFile: coverFile})
+		}
+	}
+	err = runParallel(parallelism, len(runs), func(i int) error {
+		run := runs[i]
+		cmd := exec.Command("go", run.args(testFlags)...)
 		cmd.Stdin = nil
 		// Write all test command output to stderr so as not to interfere with
 		// the JSON coverage output.
-		cmd.Stdout = os.Stderr
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
+		stderr := output.writer()
+		defer output.flush(stderr)
+		cmd.Stdout = stderr
+		cmd.Stderr = stderr
+		return failures.check(run.pkg, run.name(), cmd.Run())
+	})
+	if err != nil {
+		return err
+	}
+
+	var branches *instrument.Overlay
+	var outcomeFiles []string
+	if *testBranchesFlag {
+		branches, outcomeFiles, err = runBranchTests(pkgs, testFlags, tmpDir, parallelism, output, &failures)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Packages without tests will not produce a coverprofile; only pick up the
-	// ones that were created.
-	files, err := filepath.Glob(filepath.Join(tmpDir, "test*.cov"))
+	// ones that were created. With -keep-going, this includes the profiles
+	// of packages whose tests failed.
+	var files []string
+	testFiles := make(map[string]string)
+	for _, run := range runs {
+		if _, err := os.Stat(run.coverFile); err != nil {
+			continue
+		}
+		if run.test != "" {
+			testFiles[run.name()] = run.coverFile
+		} else {
+			files = append(files, run.coverFile)
+		}
+	}
+
+	// Merge the profiles.
+	config := newConvertConfig(*testExcludeFlag, *testSkipGeneratedFlag)
+	var out []byte
+	if *testPerTestFlag {
+		out, err = config.ConvertTestProfiles(testFiles, files...)
+	} else {
+		out, err = config.ConvertProfiles(files...)
+	}
+	if err == nil && branches != nil {
+		coverpkg := len(testflag.Remove(testFlags, "coverpkg")) != len(testFlags)
+		out, err = addBranches(out, branches, pkgs, outcomeFiles, coverpkg)
+	}
+	stdout.Write(out)
 	if err != nil {
 		return err
 	}
+	return failures.summarize(stderr, len(pkgs))
+}
 
-	// Merge the profiles.
-	out, err := convert.ConvertProfiles(files...)
-	os.Stdout.Write(out)
-	return err
+// runBranchTests runs the tests of pkgs with their sources instrumented
+// to record branch outcomes, returning the instrumented sources and the
+// names of the files, one for each package, to which the outcomes were
+// written. The cover tool does not read overlaid files, so this is done
+// separately from the tests that record statement coverage, and without
+// the cover flags: the tests of every package are run a second time.
+func runBranchTests(pkgs, testFlags []string, tmpDir string, parallelism int, output *testOutput, failures *testFailures) (*instrument.Overlay, []string, error) {
+	if len(testflag.Remove(testFlags, "overlay")) != len(testFlags) {
+		return nil, nil, fmt.Errorf("-branches cannot be used with -overlay")
+	}
+	dir := filepath.Join(tmpDir, "branches")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	overlay, err := instrument.Instrument(dir, pkgs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	testFlags, testArgs := splitArgs(testflag.Remove(testFlags, "cover", "covermode", "coverpkg", "coverprofile"))
+	outcomeFiles := make([]string, len(pkgs))
+	err = runParallel(parallelism, len(pkgs), func(i int) error {
+		outcomeFiles[i] = filepath.Join(tmpDir, fmt.Sprintf("branches%d.out", i))
+		args := append([]string{"test", "-overlay", overlay.File}, testFlags...)
+		args = append(args, pkgs[i])
+		cmd := exec.Command("go", append(args, testArgs...)...)
+		cmd.Env = append(os.Environ(), instrument.EnvVar+"="+outcomeFiles[i])
+		stderr := output.writer()
+		defer output.flush(stderr)
+		cmd.Stdout = stderr
+		cmd.Stderr = stderr
+		return failures.check(pkgs[i], pkgs[i]+" (branches)", cmd.Run())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return overlay, outcomeFiles, nil
+}
+
+// addBranches adds the branch outcomes recorded by the tests of pkgs in
+// outcomeFiles to the converted coverage. As with statement coverage,
+// the outcomes in a package are those recorded by its own tests, or
+// with -coverpkg, by the tests of any of the packages.
+func addBranches(data []byte, overlay *instrument.Overlay, pkgs, outcomeFiles []string, coverpkg bool) ([]byte, error) {
+	packages, err := unmarshalJson(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, filename := range outcomeFiles {
+		pkgPath := pkgs[i]
+		if coverpkg {
+			pkgPath = ""
+		}
+		if err := overlay.ReadOutcomes(filename, pkgPath); err != nil {
+			return nil, err
+		}
+	}
+	overlay.Apply(packages)
+	var buf bytes.Buffer
+	if err := marshalJson(&buf, packages); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,392 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/axw/gocov/gocov/internal/testflag"
+)
+
+// setTestFlag sets the named gocov test flag for the duration of a test.
+func setTestFlag(t *testing.T, name, value string) {
+	old := testFlags.Lookup(name).Value.String()
+	if err := testFlags.Set(name, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testFlags.Set(name, old) })
+}
+
+func TestParseTestFlagsPassesP(t *testing.T) {
+	setTestFlag(t, "jobs", "1")
+	rest, err := parseTestFlags([]string{"-jobs", "2", "-p", "4", "-v", "./...", "-args", "-jobs", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *testJobsFlag != 2 {
+		t.Errorf("-jobs = %d, want 2", *testJobsFlag)
+	}
+	want := []string{"-p", "4", "-v", "./...", "-args", "-jobs", "3"}
+	if !reflect.DeepEqual(rest, want) {
+		t.Fatalf("rest = %q, want %q", rest, want)
+	}
+
+	pkgs, flags := testflag.Split(rest)
+	if !reflect.DeepEqual(pkgs, []string{"./..."}) {
+		t.Errorf("packages = %q, want [./...]", pkgs)
+	}
+	run := &testRun{pkg: "example.com/a", test: "TestA", coverFile: "test0.cov"}
+	got := run.args(flags)
+	want = []string{
+		"test", "-coverprofile", "test0.cov", "-p", "4", "-v",
+		"-run", "^TestA$", "example.com/a", "-args", "-jobs", "3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	for _, p := range []int{1, 3, 8} {
+		var (
+			mu              sync.Mutex
+			running, maxRan int
+		)
+		results := make([]int, 20)
+		err := runParallel(p, len(results), func(i int) error {
+			mu.Lock()
+			running++
+			if running > maxRan {
+				maxRan = running
+			}
+			mu.Unlock()
+			results[i] = i * i
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("p=%d: %v", p, err)
+		}
+		if maxRan > p {
+			t.Errorf("p=%d: %d calls ran at once", p, maxRan)
+		}
+		for i, r := range results {
+			if r != i*i {
+				t.Errorf("p=%d: results[%d] = %d, want %d", p, i, r, i*i)
+			}
+		}
+	}
+}
+
+func TestRunParallelError(t *testing.T) {
+	failure := errors.New("failed")
+	var (
+		mu     sync.Mutex
+		called []int
+	)
+	err := runParallel(1, 10, func(i int) error {
+		mu.Lock()
+		called = append(called, i)
+		mu.Unlock()
+		if i == 3 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(called, want) {
+		t.Errorf("called %v, want %v", called, want)
+	}
+
+	// With several workers, calls already running finish, but
+	// no more than p-1 calls start after the failure.
+	called = nil
+	err = runParallel(4, 100, func(i int) error {
+		mu.Lock()
+		called = append(called, i)
+		mu.Unlock()
+		if i == 0 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if len(called) >= 100 {
+		t.Errorf("all %d calls were made after a failure", len(called))
+	}
+}
+
+func TestTestOutput(t *testing.T) {
+	var buf bytes.Buffer
+	output := newTestOutput(&buf, true)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := output.writer()
+			defer output.flush(w)
+			<-start
+			for i := 0; i < 100; i++ {
+				w.Write([]byte(name))
+			}
+			w.Write([]byte("\n"))
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		if line != strings.Repeat(line[:1], 100) {
+			t.Errorf("interleaved output: %q", line)
+		}
+	}
+}
+
+func TestTestOutputUnbuffered(t *testing.T) {
+	var buf bytes.Buffer
+	output := newTestOutput(&buf, false)
+	w := output.writer()
+	w.Write([]byte("ok\n"))
+	if buf.String() != "ok\n" {
+		t.Errorf("output before flush = %q, want it written directly", buf.String())
+	}
+	output.flush(w)
+	if buf.String() != "ok\n" {
+		t.Errorf("output after flush = %q, want %q", buf.String(), "ok\n")
+	}
+}
+
+func TestTestFailures(t *testing.T) {
+	failure := errors.New("exit status 1")
+
+	setTestFlag(t, "keep-going", "false")
+	var failures testFailures
+	if err := failures.check("example.com/b", "example.com/b", failure); err != failure {
+		t.Errorf("check without -keep-going = %v, want %v", err, failure)
+	}
+	if err := failures.summarize(new(bytes.Buffer), 4); err != nil {
+		t.Errorf("summarize without failures = %v", err)
+	}
+
+	setTestFlag(t, "keep-going", "true")
+	for _, name := range []string{"example.com/b.TestY", "example.com/b.TestX"} {
+		if err := failures.check("example.com/b", name, failure); err != nil {
+			t.Errorf("check with -keep-going = %v", err)
+		}
+	}
+	if err := failures.check("example.com/c", "example.com/c", nil); err != nil {
+		t.Errorf("check(nil) = %v", err)
+	}
+	var buf bytes.Buffer
+	err := failures.summarize(&buf, 4)
+	if err == nil || err.Error() != "tests failed in 1 of 4 packages" {
+		t.Errorf("summarize = %v, want %q", err, "tests failed in 1 of 4 packages")
+	}
+	want := "FAIL\texample.com/b.TestX\nFAIL\texample.com/b.TestY\n"
+	if buf.String() != want {
+		t.Errorf("summary:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+// writeModule writes the given files to a new module named example.com/m,
+// and changes to its directory for the duration of the test.
+func writeModule(t *testing.T, files map[string]string) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.22\n"
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "off")
+}
+
+func TestRunTestsKeepGoing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go test invocations in short mode")
+	}
+	files := map[string]string{"d/d.go": "package d\n\nfunc D() int { return 4 }\n"}
+	for _, name := range []string{"a", "b", "c"} {
+		files[name+"/"+name+".go"] = "package " + name + "\n\nfunc F() int { return 1 }\n"
+		result := "1"
+		if name == "b" {
+			result = "2"
+		}
+		files[name+"/"+name+"_test.go"] = "package " + name + "\n\nimport \"testing\"\n\n" +
+			"func TestF(t *testing.T) {\n\tif F() != " + result + " {\n\t\tt.Fatal(F())\n\t}\n}\n"
+	}
+	writeModule(t, files)
+	setTestFlag(t, "keep-going", "true")
+	setTestFlag(t, "jobs", "2")
+
+	var stdout, stderr bytes.Buffer
+	err := runTests([]string{"./..."}, &stdout, &stderr)
+	if err == nil || err.Error() != "tests failed in 1 of 4 packages" {
+		t.Fatalf("runTests = %v, want %q\n%s", err, "tests failed in 1 of 4 packages", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "FAIL\texample.com/m/b\n") {
+		t.Errorf("failed package not summarized:\n%s", stderr.String())
+	}
+
+	packages, err := unmarshalJson(stdout.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reached := make(map[string]int64)
+	for _, pkg := range packages {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				reached[pkg.Name] += stmt.Reached
+			}
+		}
+	}
+	for _, name := range []string{"example.com/m/a", "example.com/m/c"} {
+		if reached[name] == 0 {
+			t.Errorf("no coverage recorded for passing package %s", name)
+		}
+	}
+
+	// Without -keep-going, the first failure is returned as is.
+	setTestFlag(t, "keep-going", "false")
+	stdout.Reset()
+	err = runTests([]string{"./..."}, &stdout, new(bytes.Buffer))
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Errorf("runTests without -keep-going = %v, want exit error", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("coverage written after failure: %s", stdout.String())
+	}
+}
+
+func TestRunTestsPerTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go test invocations in short mode")
+	}
+	writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nfunc F(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+		"a/a_test.go": "package a\n\nimport \"testing\"\n\n" +
+			"func TestPositive(t *testing.T) { F(1) }\n\nfunc TestZero(t *testing.T) { F(0) }\n",
+		"b/b.go": "package b\n\nfunc G() int { return 2 }\n",
+	})
+	setTestFlag(t, "per-test", "true")
+
+	var stdout, stderr bytes.Buffer
+	if err := runTests([]string{"./..."}, &stdout, &stderr); err != nil {
+		t.Fatalf("runTests: %v\n%s", err, stderr.String())
+	}
+	packages, err := unmarshalJson(stdout.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := make(map[string][]string)
+	for _, pkg := range packages {
+		for _, fn := range pkg.Functions {
+			tests[pkg.Name+"."+fn.Name] = fn.Tests()
+		}
+	}
+	want := map[string][]string{
+		"example.com/m/a.F": {"example.com/m/a.TestPositive", "example.com/m/a.TestZero"},
+		// The package without tests is kept, with no coverage.
+		"example.com/m/b.G": nil,
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("got tests %q, want %q", tests, want)
+	}
+}
+
+func TestRunTestsBranches(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go test invocations in short mode")
+	}
+	writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\nfunc F(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+		"a/a_test.go": "package a\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F(1) }\n",
+		"b/b.go":      "package b\n\nimport \"example.com/m/a\"\n\nfunc G() int { return a.F(0) }\n",
+		"b/b_test.go": "package b\n\nimport \"testing\"\n\nfunc TestG(t *testing.T) { G() }\n",
+	})
+	setTestFlag(t, "branches", "true")
+
+	taken := func(args ...string) []bool {
+		var stdout, stderr bytes.Buffer
+		if err := runTests(args, &stdout, &stderr); err != nil {
+			t.Fatalf("runTests: %v\n%s", err, stderr.String())
+		}
+		packages, err := unmarshalJson(stdout.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pkg := range packages {
+			for _, fn := range pkg.Functions {
+				if pkg.Name == "example.com/m/a" && fn.Name == "F" && len(fn.Branches) == 1 {
+					return fn.Branches[0].Taken
+				}
+			}
+		}
+		t.Fatalf("no branch recorded for a.F:\n%s", stdout.String())
+		return nil
+	}
+
+	// Like its statements, the branches of a are only reached by its
+	// own tests, and not those of b, unless -coverpkg is given.
+	if got := taken("./..."); !reflect.DeepEqual(got, []bool{true, false}) {
+		t.Errorf("branch outcomes %v, want [true false]", got)
+	}
+	if got := taken("-coverpkg=./...", "./..."); !reflect.DeepEqual(got, []bool{true, true}) {
+		t.Errorf("with -coverpkg, branch outcomes %v, want [true true]", got)
+	}
+}
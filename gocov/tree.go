@@ -0,0 +1,152 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	treeFlags     = flag.NewFlagSet("tree", flag.ExitOnError)
+	treeDepthFlag = treeFlags.Int(
+		"depth", 0,
+		"Collapse directories more than the specified number of path elements deep into their ancestors (0 for no limit)")
+)
+
+// treeNode is a directory in the tree of package paths, with the
+// statement coverage of all the packages within it.
+type treeNode struct {
+	name           string
+	reached, total int
+	children       map[string]*treeNode
+
+	// pkg records whether the directory holds a package of its own,
+	// or packages in subdirectories that were collapsed into it.
+	pkg bool
+}
+
+func newTreeNode(name string) *treeNode {
+	return &treeNode{name: name, children: make(map[string]*treeNode)}
+}
+
+// add adds the coverage of the package with the given path to the
+// node and each of its descendants along the path.
+func (n *treeNode) add(elems []string, reached, total int) {
+	n.reached += reached
+	n.total += total
+	if len(elems) == 0 {
+		n.pkg = true
+		return
+	}
+	child := n.children[elems[0]]
+	if child == nil {
+		child = newTreeNode(elems[0])
+		n.children[elems[0]] = child
+	}
+	child.add(elems[1:], reached, total)
+}
+
+// compact joins each directory that holds no packages of its own and
+// only one subdirectory with that subdirectory, so that a path prefix
+// such as "github.com/user/repo" is shown on one line.
+func (n *treeNode) compact() {
+	for len(n.children) == 1 && !n.pkg {
+		for _, child := range n.children {
+			n.name += "/" + child.name
+			n.children = child.children
+			n.pkg = child.pkg
+		}
+	}
+	for _, child := range n.children {
+		child.compact()
+	}
+}
+
+// sortedChildren returns the node's children in order of name.
+func (n *treeNode) sortedChildren() []*treeNode {
+	children := make([]*treeNode, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+// buildTree returns the tree of the report's package paths. If depth
+// is positive, directories more than depth path elements deep are
+// collapsed into their ancestor at that depth, before directories are
+// joined by compact.
+func buildTree(r *report, depth int) *treeNode {
+	root := newTreeNode("")
+	for _, pkg := range r.packages {
+		reached, total := statementCoverage(pkg.Functions...)
+		elems := strings.Split(pkg.Name, "/")
+		if depth > 0 && len(elems) > depth {
+			elems = elems[:depth]
+		}
+		root.add(elems, reached, total)
+	}
+	for _, child := range root.children {
+		child.compact()
+	}
+	return root
+}
+
+// printTree prints the coverage of each directory in the tree, indented
+// by its level in the tree, followed by the total coverage.
+func printTree(w io.Writer, root *treeNode) {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	var walk func(n *treeNode, level int)
+	walk = func(n *treeNode, level int) {
+		fmt.Fprintf(tw, "%s%s\t %.2f%% (%d/%d)\n",
+			strings.Repeat("  ", level), n.name,
+			percentage(n.reached, n.total), n.reached, n.total)
+		for _, child := range n.sortedChildren() {
+			walk(child, level+1)
+		}
+	}
+	for _, child := range root.sortedChildren() {
+		walk(child, 0)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Total Coverage: %.2f%% (%d/%d)\n",
+		percentage(root.reached, root.total), root.reached, root.total)
+}
+
+func treeReport() (rc int) {
+	treeFlags.Parse(flag.Args()[1:])
+	report, err := readReport(treeFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printTree(os.Stdout, buildTree(report, *treeDepthFlag))
+	return 0
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestTreeCompact(t *testing.T) {
+	r := testReport(t,
+		&gocov.Package{Name: "github.com/user/repo", Functions: []*gocov.Function{
+			testFunction("F", "/src/repo/repo.go", 1, 1, 0),
+		}},
+		// A package without statements is still shown.
+		&gocov.Package{Name: "github.com/user/repo/empty", Functions: []*gocov.Function{
+			testFunction("init", "/src/repo/empty/empty.go", 1),
+		}},
+		&gocov.Package{Name: "github.com/user/repo/empty/sub", Functions: []*gocov.Function{
+			testFunction("G", "/src/repo/empty/sub/sub.go", 1, 1),
+		}},
+		&gocov.Package{Name: "github.com/user/repo/internal/a/b", Functions: []*gocov.Function{
+			testFunction("H", "/src/repo/internal/a/b/b.go", 1, 0, 0),
+		}},
+	)
+	var buf bytes.Buffer
+	printTree(&buf, buildTree(r, 0))
+	expected := `github.com/user/repo  40.00% (2/5)
+  empty               100.00% (1/1)
+    sub               100.00% (1/1)
+  internal/a/b        0.00% (0/2)
+
+Total Coverage: 40.00% (2/5)
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// Directories more than four elements deep are collapsed.
+	buf.Reset()
+	printTree(&buf, buildTree(r, 4))
+	expected = `github.com/user/repo  40.00% (2/5)
+  empty               100.00% (1/1)
+  internal            0.00% (0/2)
+
+Total Coverage: 40.00% (2/5)
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestTreeDepth(t *testing.T) {
+	r := testReport(t,
+		&gocov.Package{Name: "example.com/a/b/c/d/e/f", Functions: []*gocov.Function{
+			testFunction("F", "/src/f/f.go", 1, 1, 0),
+		}},
+		&gocov.Package{Name: "example.com/x", Functions: []*gocov.Function{
+			testFunction("G", "/src/x/x.go", 1, 1),
+		}},
+	)
+	tests := []struct {
+		depth    int
+		expected string
+	}{{
+		depth: 0,
+		expected: `example.com    66.67% (2/3)
+  a/b/c/d/e/f  50.00% (1/2)
+  x            100.00% (1/1)
+`,
+	}, {
+		// The depth applies to the package paths, not to the
+		// levels of the compacted tree.
+		depth: 3,
+		expected: `example.com  66.67% (2/3)
+  a/b        50.00% (1/2)
+  x          100.00% (1/1)
+`,
+	}, {
+		depth: 1,
+		expected: `example.com  66.67% (2/3)
+`,
+	}}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		printTree(&buf, buildTree(r, test.depth))
+		expected := test.expected + "\nTotal Coverage: 66.67% (2/3)\n"
+		if buf.String() != expected {
+			t.Errorf("depth %d: expected:\n%s\ngot:\n%s", test.depth, expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/axw/gocov"
+	goPackages "golang.org/x/tools/go/packages"
+)
+
+// uncalledFilter restricts the functions listed by printUncalled.
+type uncalledFilter struct {
+	// exported restricts the list to exported functions, and
+	// exported methods of exported types.
+	exported bool
+
+	// interfaceMethods, if non-nil, restricts the list to the methods
+	// that implement an interface method, keyed by package and then
+	// function name; see loadInterfaceMethods.
+	interfaceMethods map[string]map[string]bool
+}
+
+func (f *uncalledFilter) include(pkg *gocov.Package, fn *gocov.Function) bool {
+	if f.exported && !isExportedFunction(fn.Name) {
+		return false
+	}
+	if f.interfaceMethods != nil && !f.interfaceMethods[pkg.Name][fn.Name] {
+		return false
+	}
+	return true
+}
+
+// reportUncalled prints the functions in the report that were never
+// called, filtered according to the -exported and -interfaces flags.
+func reportUncalled(w io.Writer, r *report) error {
+	filter := &uncalledFilter{exported: *reportExportedFlag}
+	if *reportInterfacesFlag {
+		var pkgPaths []string
+		for _, pkg := range r.packages {
+			pkgPaths = append(pkgPaths, pkg.Name)
+		}
+		methods, err := loadInterfaceMethods(pkgPaths)
+		if err != nil {
+			return err
+		}
+		filter.interfaceMethods = methods
+	}
+	printUncalled(w, r, filter)
+	return nil
+}
+
+// printUncalled lists the functions in the report that were never
+// called, which is to say that none of their statements were reached,
+// grouped by package and file. Functions without statements are not
+// listed, as there is no telling whether they were called.
+func printUncalled(w io.Writer, r *report, filter *uncalledFilter) {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', tabwriter.TabIndent)
+	for _, pkg := range r.packages {
+		printedPackage := false
+		for _, file := range packageFiles(pkg) {
+			for _, fn := range file.functions {
+				if reached, total := statementCoverage(fn); reached > 0 || total == 0 {
+					continue
+				}
+				if !filter.include(pkg, fn) {
+					continue
+				}
+				if !printedPackage {
+					fmt.Fprintln(tw, pkg.Name)
+					printedPackage = true
+				}
+				fmt.Fprintf(tw, "\t%s:%d\t%s\n", filepath.Base(file.name), fn.StartLine, fn.Name)
+			}
+		}
+	}
+	tw.Flush()
+}
+
+// splitFunctionName splits a function name of the form produced by
+// convert into the receiver type name, without any type parameters,
+// and the function or method name. The receiver is empty for
+// functions.
+func splitFunctionName(name string) (recv, fn string) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", name
+	}
+	recv, fn = name[:i], name[i+1:]
+	if j := strings.Index(recv, "["); j >= 0 {
+		recv = recv[:j]
+	}
+	return recv, fn
+}
+
+// isExportedFunction reports whether the named function is exported,
+// or is an exported method of an exported type. Function literals are
+// never exported.
+func isExportedFunction(name string) bool {
+	recv, fn := splitFunctionName(name)
+	return token.IsExported(fn) && (recv == "" || token.IsExported(recv))
+}
+
+// loadInterfaceMethods returns, for each of the named packages, the
+// names of the methods of its types that implement a method of a
+// non-empty interface declared in the package, in one of the packages
+// it imports directly or indirectly, or in the universe scope (error).
+// Methods of generic types are not considered.
+func loadInterfaceMethods(pkgPaths []string) (map[string]map[string]bool, error) {
+	pkgs, err := goPackages.Load(&goPackages.Config{
+		Mode: goPackages.NeedName | goPackages.NeedTypes | goPackages.NeedImports,
+	}, pkgPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %v", err)
+	}
+	result := make(map[string]map[string]bool)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		result[pkg.PkgPath] = interfaceMethods(pkg.Types)
+	}
+	return result, nil
+}
+
+// interfaceMethods returns the names of the methods of the types
+// declared in pkg, or pointers to them, that implement a method of an
+// interface returned by collectInterfaces.
+func interfaceMethods(pkg *types.Package) map[string]bool {
+	interfaces := collectInterfaces(pkg)
+	methods := make(map[string]bool)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok || types.IsInterface(named) || named.TypeParams().Len() > 0 {
+			continue
+		}
+		ptr := types.NewPointer(named)
+		for _, iface := range interfaces {
+			if !types.Implements(named, iface) && !types.Implements(ptr, iface) {
+				continue
+			}
+			for i := 0; i < iface.NumMethods(); i++ {
+				methods[name+"."+iface.Method(i).Name()] = true
+			}
+		}
+	}
+	return methods
+}
+
+// collectInterfaces returns the non-generic, non-empty interface types
+// declared in pkg and the packages it imports, and the error interface.
+func collectInterfaces(pkg *types.Package) []*types.Interface {
+	interfaces := []*types.Interface{
+		types.Universe.Lookup("error").Type().Underlying().(*types.Interface),
+	}
+	seen := make(map[*types.Package]bool)
+	var visit func(*types.Package)
+	visit = func(p *types.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		scope := p.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			if iface, ok := tn.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+				interfaces = append(interfaces, iface)
+			}
+		}
+		for _, imp := range p.Imports() {
+			visit(imp)
+		}
+	}
+	visit(pkg)
+	return interfaces
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestSplitFunctionName(t *testing.T) {
+	tests := []struct {
+		name, recv, fn string
+	}{
+		{"F", "", "F"},
+		{"T.M", "T", "M"},
+		{"T[K].M", "T", "M"},
+		{"t.M", "t", "M"},
+		{"@12:5", "", "@12:5"},
+	}
+	for _, test := range tests {
+		recv, fn := splitFunctionName(test.name)
+		if recv != test.recv || fn != test.fn {
+			t.Errorf("splitFunctionName(%q) = %q, %q; want %q, %q", test.name, recv, fn, test.recv, test.fn)
+		}
+	}
+}
+
+func TestIsExportedFunction(t *testing.T) {
+	tests := []struct {
+		name     string
+		exported bool
+	}{
+		{"F", true},
+		{"f", false},
+		{"T.M", true},
+		{"T[K].M", true},
+		{"T.m", false},
+		{"t.M", false},
+		{"@12:5", false},
+	}
+	for _, test := range tests {
+		if exported := isExportedFunction(test.name); exported != test.exported {
+			t.Errorf("isExportedFunction(%q) = %v, want %v", test.name, exported, test.exported)
+		}
+	}
+}
+
+// uncalledReport returns a report with a package whose functions are
+// called or not, exported or not, and implement interface methods or
+// not, as their names describe.
+func uncalledReport(t *testing.T) *report {
+	return testReport(t, &gocov.Package{Name: "example.com/p", Functions: []*gocov.Function{
+		testFunction("Called", "/src/p/p.go", 1, 1),
+		testFunction("F", "/src/p/p.go", 10, 0),
+		testFunction("f", "/src/p/p.go", 20, 0, 0),
+		testFunction("Empty", "/src/p/p.go", 30),
+		testFunction("@41:6", "/src/p/p.go", 40, 0),
+		testFunction("File.Close", "/src/p/file.go", 1, 0),
+		testFunction("File.Other", "/src/p/file.go", 10, 0),
+		testFunction("runner.Run", "/src/p/runner.go", 1, 0),
+		testFunction("runner.helper", "/src/p/runner.go", 10, 0),
+	}})
+}
+
+// uncalledNames returns the names of the functions listed by
+// printUncalled, in order.
+func uncalledNames(t *testing.T, filter *uncalledFilter) []string {
+	var buf bytes.Buffer
+	printUncalled(&buf, uncalledReport(t), filter)
+	var names []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			names = append(names, fields[0]+" "+fields[1])
+		}
+	}
+	return names
+}
+
+func TestPrintUncalled(t *testing.T) {
+	var buf bytes.Buffer
+	printUncalled(&buf, uncalledReport(t), &uncalledFilter{})
+	want := "example.com/p\n" +
+		"\tfile.go:1    File.Close\n" +
+		"\tfile.go:10   File.Other\n" +
+		"\tp.go:10      F\n" +
+		"\tp.go:20      f\n" +
+		"\tp.go:40      @41:6\n" +
+		"\trunner.go:1  runner.Run\n" +
+		"\trunner.go:10 runner.helper\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	interfaces := map[string]map[string]bool{
+		"example.com/p": {"File.Close": true, "runner.Run": true},
+	}
+	tests := []struct {
+		filter *uncalledFilter
+		want   []string
+	}{{
+		filter: &uncalledFilter{},
+		want: []string{
+			"file.go:1 File.Close", "file.go:10 File.Other",
+			"p.go:10 F", "p.go:20 f", "p.go:40 @41:6",
+			"runner.go:1 runner.Run", "runner.go:10 runner.helper",
+		},
+	}, {
+		filter: &uncalledFilter{exported: true},
+		want:   []string{"file.go:1 File.Close", "file.go:10 File.Other", "p.go:10 F"},
+	}, {
+		filter: &uncalledFilter{interfaceMethods: interfaces},
+		want:   []string{"file.go:1 File.Close", "runner.go:1 runner.Run"},
+	}, {
+		filter: &uncalledFilter{exported: true, interfaceMethods: interfaces},
+		want:   []string{"file.go:1 File.Close"},
+	}, {
+		// A package that was not loaded has no interface methods.
+		filter: &uncalledFilter{interfaceMethods: map[string]map[string]bool{}},
+	}}
+	for _, test := range tests {
+		if got := uncalledNames(t, test.filter); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("filter %+v: got %q, want %q", test.filter, got, test.want)
+		}
+	}
+}
+
+// testImporter imports packages that have already been checked.
+type testImporter map[string]*types.Package
+
+func (m testImporter) Import(path string) (*types.Package, error) {
+	return m[path], nil
+}
+
+// checkPackage type-checks a package from source.
+func checkPackage(t *testing.T, path, src string, imports testImporter) *types.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path+".go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: imports}
+	pkg, err := conf.Check(path, fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports[path] = pkg
+	return pkg
+}
+
+func TestInterfaceMethods(t *testing.T) {
+	imports := make(testImporter)
+	checkPackage(t, "example.com/dep", `package dep
+
+type Closer interface{ Close() error }
+
+type Any interface{}
+
+type Getter[T any] interface{ Get() T }
+`, imports)
+	pkg := checkPackage(t, "example.com/p", `package p
+
+import "example.com/dep"
+
+var _ dep.Closer
+
+type Runner interface{ Run() }
+
+// File implements dep.Closer with a pointer receiver.
+type File struct{}
+
+func (f *File) Close() error { return nil }
+func (f *File) Other()       {}
+func (f *File) Get() int     { return 0 }
+
+type Err struct{}
+
+func (Err) Error() string { return "" }
+
+type runner struct{}
+
+func (runner) Run()    {}
+func (runner) helper() {}
+
+type Generic[T any] struct{}
+
+func (Generic[T]) Run() {}
+
+type Alias = runner
+`, imports)
+
+	methods := interfaceMethods(pkg)
+	var names []string
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"Err.Error", "File.Close", "runner.Run"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+}
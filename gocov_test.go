@@ -20,7 +20,10 @@
 
 package gocov
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func registerPackage(name string) *Package {
 	return &Package{Name: name}
@@ -136,3 +139,293 @@ func TestAccumulateStatement(t *testing.T) {
 		t.Errorf("Expected an error")
 	}
 }
+
+func TestMergeModes(t *testing.T) {
+	var tests = [...]struct {
+		mode     MergeMode
+		a, b     int64
+		expected int64
+	}{
+		{MergeSum, 2, 3, 5},
+		{MergeMax, 2, 3, 3},
+		{MergeMax, 3, 2, 3},
+		{MergeSet, 2, 3, 1},
+		{MergeSet, 0, 3, 1},
+		{MergeSet, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		p1 := registerPackage("p1")
+		f1 := registerFunction(p1, "f", "file.go", 0, 1)
+		s1 := registerStatement(f1, 0, 1)
+		s1.Reached = test.a
+		p2 := registerPackage("p1")
+		f2 := registerFunction(p2, "f", "file.go", 0, 1)
+		s2 := registerStatement(f2, 0, 1)
+		s2.Reached = test.b
+		if err := p1.Merge(p2, test.mode); err != nil {
+			t.Error(err)
+		}
+		if s1.Reached != test.expected {
+			t.Errorf("%s(%d, %d): expected %d, got %d", test.mode, test.a, test.b, test.expected, s1.Reached)
+		}
+	}
+}
+
+func TestMergeMismatch(t *testing.T) {
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 0, 10)
+	s1 := registerStatement(f1, 0, 1)
+	s1.Reached = 1
+	registerStatement(f1, 2, 3)
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 0, 10)
+	s2 := registerStatement(f2, 0, 1)
+	s2.Reached = 1
+	registerStatement(f2, 4, 5)
+
+	// Should fail, and leave the first statement untouched.
+	if err := p1.Merge(p2, MergeSum); err == nil {
+		t.Error("Expected an error")
+	}
+	if s1.Reached != 1 {
+		t.Errorf("Expected package to be unmodified, Reached=%d", s1.Reached)
+	}
+}
+
+func TestParseMergeMode(t *testing.T) {
+	for _, mode := range []MergeMode{MergeSum, MergeMax, MergeSet} {
+		parsed, err := ParseMergeMode(mode.String())
+		if err != nil {
+			t.Error(err)
+		} else if parsed != mode {
+			t.Errorf("Expected %s, got %s", mode, parsed)
+		}
+	}
+	if _, err := ParseMergeMode("min"); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 10, 50)
+	registerStatement(f1, 15, 20)
+	registerStatement(f1, 25, 30)
+	registerStatement(f1, 40, 45)
+	g1 := registerFunction(p1, "g", "file.go", 60, 70)
+	registerStatement(g1, 62, 68)
+
+	// f has moved down by 5 bytes, and had a statement inserted
+	// in the middle; h does not exist in p1.
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 15, 62)
+	registerStatement(f2, 20, 25).Reached = 1
+	registerStatement(f2, 32, 38).Reached = 1
+	registerStatement(f2, 52, 57).Reached = 1
+	h2 := registerFunction(p2, "h", "file.go", 80, 90)
+	registerStatement(h2, 82, 88).Reached = 1
+
+	mismatches := p1.Reconcile(p2, MergeSum)
+	if len(mismatches) != 2 {
+		t.Fatalf("Expected 2 mismatches, got %v", mismatches)
+	}
+	if mismatches[0].Statement != f2.Statements[1] {
+		t.Errorf("Expected inserted statement to be unmatched, got %v", mismatches[0])
+	}
+	if mismatches[1].Function != h2 || mismatches[1].Statement != nil {
+		t.Errorf("Expected function h to be unmatched, got %v", mismatches[1])
+	}
+
+	var reached []int64
+	for _, s := range f1.Statements {
+		reached = append(reached, s.Reached)
+	}
+	if reached[0] != 1 || reached[1] != 0 || reached[2] != 1 {
+		t.Errorf("Unexpected statement counts: %v", reached)
+	}
+	if len(p1.Functions) != 2 || g1.Statements[0].Reached != 0 {
+		t.Errorf("Expected g to be unmodified")
+	}
+}
+
+func TestReconcileNameMismatch(t *testing.T) {
+	p1 := registerPackage("p1")
+	p2 := registerPackage("p2")
+	if mismatches := p1.Reconcile(p2, MergeSum); len(mismatches) != 1 {
+		t.Errorf("Expected a mismatch, got %v", mismatches)
+	}
+}
+
+func TestReconcileLines(t *testing.T) {
+	// A statement whose offsets differ because an earlier line in the
+	// function grew, but whose line and column are unchanged.
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 0, 100)
+	f1.StartLine = 1
+	s1 := registerStatement(f1, 50, 60)
+	s1.StartLine, s1.StartCol, s1.EndLine, s1.EndCol = 5, 2, 5, 12
+
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 0, 90)
+	f2.StartLine = 1
+	s2 := registerStatement(f2, 45, 55)
+	s2.StartLine, s2.StartCol, s2.EndLine, s2.EndCol = 5, 2, 5, 12
+	s2.Reached = 3
+
+	if mismatches := p1.Reconcile(p2, MergeSum); len(mismatches) != 0 {
+		t.Errorf("Unexpected mismatches: %v", mismatches)
+	}
+	if s1.Reached != 3 {
+		t.Errorf("Expected statement to be matched by line, Reached=%d", s1.Reached)
+	}
+}
+
+func TestMatchFunctions(t *testing.T) {
+	// Two functions with the same file and name, such as init
+	// functions, are matched in order.
+	p1 := registerPackage("p1")
+	init1 := registerFunction(p1, "init", "file.go", 0, 10)
+	init2 := registerFunction(p1, "init", "file.go", 20, 30)
+	registerFunction(p1, "f", "file.go", 40, 50)
+	p2 := registerPackage("p1")
+	init3 := registerFunction(p2, "init", "file.go", 0, 10)
+	init4 := registerFunction(p2, "init", "file.go", 20, 30)
+	g := registerFunction(p2, "g", "file.go", 40, 50)
+
+	matches := p1.MatchFunctions(p2)
+	if len(matches) != 2 || matches[init3] != init1 || matches[init4] != init2 {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+	if matches[g] != nil {
+		t.Errorf("Expected g to be unmatched")
+	}
+
+	// Functions are matched by the base name of their file, as the
+	// packages may come from different checkouts.
+	p3 := registerPackage("p1")
+	f := registerFunction(p3, "f", "/ci/workspace@2/p1/file.go", 40, 50)
+	matches = p1.MatchFunctions(p3)
+	if len(matches) != 1 || matches[f] != p1.Functions[2] {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+}
+
+func TestMatchStatements(t *testing.T) {
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 10, 50)
+	s1 := registerStatement(f1, 15, 20)
+	s2 := registerStatement(f1, 40, 45)
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 15, 62)
+	s3 := registerStatement(f2, 20, 25)
+	s4 := registerStatement(f2, 32, 38)
+	s5 := registerStatement(f2, 52, 57)
+
+	matches := f1.MatchStatements(f2)
+	if len(matches) != 2 || matches[s3] != s1 || matches[s5] != s2 {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+	if matches[s4] != nil {
+		t.Errorf("Expected inserted statement to be unmatched")
+	}
+}
+
+func TestReconcileFuncLits(t *testing.T) {
+	// Function literals are named after their position, so once
+	// lines are inserted before them they must be matched by their
+	// position relative to the enclosing function.
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "/a/file.go", 10, 100)
+	lit1 := registerFunction(p1, "@3:10", "/a/file.go", 30, 60)
+	registerStatement(lit1, 40, 50)
+	nested1 := registerFunction(p1, "@4:12", "/a/file.go", 42, 48)
+	registerStatement(nested1, 44, 46)
+	other1 := registerFunction(p1, "@6:10", "/a/file.go", 70, 90)
+	registerStatement(other1, 75, 85)
+
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "/b/file.go", 20, 110)
+	lit2 := registerFunction(p2, "@5:10", "/b/file.go", 40, 70)
+	registerStatement(lit2, 50, 60).Reached = 1
+	nested2 := registerFunction(p2, "@6:12", "/b/file.go", 52, 58)
+	registerStatement(nested2, 54, 56).Reached = 2
+	// A literal in a function that does not exist in p1.
+	g2 := registerFunction(p2, "g", "/b/file.go", 200, 300)
+	registerFunction(p2, "@20:10", "/b/file.go", 210, 220)
+
+	matches := p1.MatchFunctions(p2)
+	if matches[f2] != f1 || matches[lit2] != lit1 || matches[nested2] != nested1 {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+	if len(matches) != 3 || matches[g2] != nil {
+		t.Errorf("Expected g and its literal to be unmatched: %v", matches)
+	}
+
+	mismatches := p1.Reconcile(p2, MergeSum)
+	if len(mismatches) != 2 {
+		t.Errorf("Expected 2 mismatches, got %v", mismatches)
+	}
+	if lit1.Statements[0].Reached != 1 || nested1.Statements[0].Reached != 2 {
+		t.Errorf("Unexpected statement counts: %d, %d", lit1.Statements[0].Reached, nested1.Statements[0].Reached)
+	}
+	if other1.Statements[0].Reached != 0 {
+		t.Errorf("Expected other literal to be unmodified")
+	}
+}
+
+func TestMergeTests(t *testing.T) {
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 0, 10)
+	registerStatement(f1, 0, 1).Tests = []string{"TestA", "TestC"}
+	registerStatement(f1, 2, 3)
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 0, 10)
+	registerStatement(f2, 0, 1).Tests = []string{"TestB", "TestC"}
+	registerStatement(f2, 2, 3).Tests = []string{"TestD"}
+
+	if err := p1.Accumulate(p2); err != nil {
+		t.Fatal(err)
+	}
+	if tests := f1.Statements[0].Tests; !reflect.DeepEqual(tests, []string{"TestA", "TestB", "TestC"}) {
+		t.Errorf("Unexpected tests for first statement: %q", tests)
+	}
+	if tests := f1.Tests(); !reflect.DeepEqual(tests, []string{"TestA", "TestB", "TestC", "TestD"}) {
+		t.Errorf("Unexpected tests for function: %q", tests)
+	}
+}
+
+func TestMergeBranches(t *testing.T) {
+	p1 := registerPackage("p1")
+	f1 := registerFunction(p1, "f", "file.go", 0, 10)
+	registerStatement(f1, 0, 1)
+	p2 := registerPackage("p1")
+	f2 := registerFunction(p2, "f", "file.go", 0, 10)
+	registerStatement(f2, 0, 1)
+	f2.Branches = []*Branch{{Kind: "if", Taken: []bool{true, false}}, {Kind: "case", Taken: []bool{false}}}
+	p3 := registerPackage("p1")
+	f3 := registerFunction(p3, "f", "file.go", 0, 10)
+	registerStatement(f3, 0, 1)
+	f3.Branches = []*Branch{{Kind: "if", Taken: []bool{false, true}}, {Kind: "case", Taken: []bool{false}}}
+
+	for _, p := range []*Package{p2, p3} {
+		if err := p1.Merge(p, MergeSum); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var taken [][]bool
+	for _, b := range f1.Branches {
+		taken = append(taken, b.Taken)
+	}
+	if expected := [][]bool{{true, true}, {false}}; !reflect.DeepEqual(taken, expected) {
+		t.Errorf("Unexpected branches: %v", taken)
+	}
+	if f2.Branches[0].Taken[1] {
+		t.Errorf("Merge modified the merged package's branches")
+	}
+
+	f3.Branches = f3.Branches[:1]
+	if err := p1.Merge(p3, MergeSum); err == nil {
+		t.Errorf("Expected an error merging mismatched branches")
+	}
+}
@@ -14,17 +14,51 @@ import (
 type Packages []*gocov.Package
 
 // AddPackage adds a package's coverage information to the
+// set. Packages that do not match the structure of an existing
+// package with the same name are ignored; use MergePackage to
+// detect them.
 func (ps *Packages) AddPackage(p *gocov.Package) {
+	ps.MergePackage(p, gocov.MergeSum)
+}
+
+// MergePackage merges a package's coverage information into the
+// set, combining statement counts according to mode. If the set
+// already contains a package with the same name, and its structure
+// does not match p, the set is left unmodified and an error is
+// returned.
+func (ps *Packages) MergePackage(p *gocov.Package, mode gocov.MergeMode) error {
+	i := sort.Search(len(*ps), func(i int) bool {
+		return (*ps)[i].Name >= p.Name
+	})
+	if i < len(*ps) && (*ps)[i].Name == p.Name {
+		return (*ps)[i].Merge(p, mode)
+	}
+	if mode == gocov.MergeSet {
+		for _, f := range p.Functions {
+			for _, s := range f.Statements {
+				s.Reached = mode.Combine(s.Reached, 0)
+			}
+		}
+	}
+	head := (*ps)[:i]
+	tail := append([]*gocov.Package{p}, (*ps)[i:]...)
+	*ps = append(head, tail...)
+	return nil
+}
+
+// ReconcilePackage merges a package's coverage information into
+// the set like MergePackage, but tolerates changes to the source
+// between runs; see gocov.Package.Reconcile. The entries of p that
+// could not be merged are returned.
+func (ps *Packages) ReconcilePackage(p *gocov.Package, mode gocov.MergeMode) []gocov.Mismatch {
 	i := sort.Search(len(*ps), func(i int) bool {
 		return (*ps)[i].Name >= p.Name
 	})
 	if i < len(*ps) && (*ps)[i].Name == p.Name {
-		(*ps)[i].Accumulate(p)
-	} else {
-		head := (*ps)[:i]
-		tail := append([]*gocov.Package{p}, (*ps)[i:]...)
-		*ps = append(head, tail...)
+		return (*ps)[i].Reconcile(p, mode)
 	}
+	ps.MergePackage(p, mode)
+	return nil
 }
 
 // ReadPackages takes a list of filenames and parses their